@@ -0,0 +1,380 @@
+package account_scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client"
+	"github.com/centrifuge/go-substrate-rpc-client/scale"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// AccountBalance is the System.Account entry of an account at a block
+type AccountBalance struct {
+	Account    types.AccountID
+	Nonce      uint32
+	Free       *big.Int
+	Reserved   *big.Int
+	MiscFrozen *big.Int
+	FeeFrozen  *big.Int
+}
+
+// Total returns the free and reserved balance of the account
+func (b AccountBalance) Total() *big.Int {
+	return new(big.Int).Add(b.Free, b.Reserved)
+}
+
+// decodeAccountInfo decodes a System.Account entry. The reference counters between nonce and balances changed
+// over runtime versions (u8 refcount, u32 refcount, u32 consumers and providers, plus u32 sufficients),
+// so their size is derived from the length of the entry.
+func decodeAccountInfo(acc types.AccountID, data []byte) (AccountBalance, error) {
+	const balances = 4 * 16
+	counters := len(data) - 4 - balances
+	if counters != 1 && counters != 4 && counters != 8 && counters != 12 {
+		return AccountBalance{}, fmt.Errorf("unexpected AccountInfo length %d for account %x", len(data), acc)
+	}
+
+	decoder := scale.NewDecoder(bytes.NewReader(data))
+	var nonce types.U32
+	err := decoder.Decode(&nonce)
+	if err != nil {
+		return AccountBalance{}, err
+	}
+
+	// the counters are not part of the snapshot, skip them
+	err = decoder.Read(make([]byte, counters))
+	if err != nil {
+		return AccountBalance{}, err
+	}
+
+	var free, reserved, miscFrozen, feeFrozen types.U128
+	for _, target := range []*types.U128{&free, &reserved, &miscFrozen, &feeFrozen} {
+		err = decoder.Decode(target)
+		if err != nil {
+			return AccountBalance{}, err
+		}
+	}
+
+	return AccountBalance{
+		Account:    acc,
+		Nonce:      uint32(nonce),
+		Free:       free.Int,
+		Reserved:   reserved.Int,
+		MiscFrozen: miscFrozen.Int,
+		FeeFrozen:  feeFrozen.Int,
+	}, nil
+}
+
+// queryStorageAt returns the values of the keys at the given block, missing entries are left out of the result
+func queryStorageAt(ctx context.Context, conn *connection, keys []types.StorageKey, hash types.Hash) (map[string][]byte, error) {
+	hexKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		hexKeys = append(hexKeys, key.Hex())
+	}
+
+	var sets []types.StorageChangeSet
+	err := conn.call(ctx, func(api *gsrpc.SubstrateAPI) error {
+		return api.Client.Call(&sets, "state_queryStorageAt", hexKeys, hash.Hex())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte)
+	for _, set := range sets {
+		for _, change := range set.Changes {
+			if change.HasStorageData && len(change.StorageData) > 0 {
+				values[change.StorageKey.Hex()] = change.StorageData
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// stateBalances reads every entry of System.Account storage at the given block
+func stateBalances(ctx context.Context, conn *connection, hash types.Hash) ([]AccountBalance, error) {
+	prefix := storagePrefix("System", "Account")
+	var balances []AccountBalance
+	err := forEachKeysPage(ctx, conn, prefix, hash, func(keys []types.StorageKey) error {
+		values, err := queryStorageAt(ctx, conn, keys, hash)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			acc, err := accountFromKey(prefix, key)
+			if err != nil {
+				return err
+			}
+
+			data, ok := values[key.Hex()]
+			if !ok {
+				continue
+			}
+
+			balance, err := decodeAccountInfo(acc, data)
+			if err != nil {
+				return err
+			}
+			balances = append(balances, balance)
+		}
+
+		logger.Info("Read accounts", "count", len(balances))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+// accountBalances reads the System.Account entries of the accounts at the given block.
+// Accounts without entry, which were reaped or never existed at that block, are left out.
+func accountBalances(ctx context.Context, conn *connection, metas *metadataCache, accounts []types.AccountID, hash types.Hash) ([]AccountBalance, error) {
+	var meta *types.Metadata
+	err := conn.call(ctx, func(api *gsrpc.SubstrateAPI) (err error) {
+		meta, err = metas.forBlock(nodeRuntime{api}, hash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var balances []AccountBalance
+	for start := 0; start < len(accounts); start += keysPageSize {
+		end := start + keysPageSize
+		if end > len(accounts) {
+			end = len(accounts)
+		}
+
+		keys := make([]types.StorageKey, 0, end-start)
+		for _, acc := range accounts[start:end] {
+			key, err := types.CreateStorageKey(meta, "System", "Account", acc[:], nil)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+
+		values, err := queryStorageAt(ctx, conn, keys, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, acc := range accounts[start:end] {
+			data, ok := values[keys[i].Hex()]
+			if !ok {
+				continue
+			}
+
+			balance, err := decodeAccountInfo(acc, data)
+			if err != nil {
+				return nil, err
+			}
+			balances = append(balances, balance)
+		}
+	}
+
+	return balances, nil
+}
+
+// totalIssuance returns Balances.TotalIssuance at the given block
+func totalIssuance(ctx context.Context, conn *connection, metas *metadataCache, hash types.Hash) (*big.Int, error) {
+	var issuance types.U128
+	err := conn.call(ctx, func(api *gsrpc.SubstrateAPI) error {
+		meta, err := metas.forBlock(nodeRuntime{api}, hash)
+		if err != nil {
+			return err
+		}
+
+		key, err := types.CreateStorageKey(meta, "Balances", "TotalIssuance", nil, nil)
+		if err != nil {
+			return err
+		}
+
+		ok, err := api.RPC.State.GetStorage(key, &issuance, hash)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no Balances.TotalIssuance at block %s", hash.Hex())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return issuance.Int, nil
+}
+
+// saveBalances writes the balances of the accounts at the given block to path and checks their total
+// against Balances.TotalIssuance. A total exceeding the issuance is an error, a lower one means accounts
+// holding the difference are not in the list.
+func saveBalances(ctx context.Context, conn *connection, metas *metadataCache, accounts []types.AccountID, number uint64, path string,
+	format Format, prefix uint16) error {
+	var hash types.Hash
+	err := conn.call(ctx, func(api *gsrpc.SubstrateAPI) (err error) {
+		hash, err = api.RPC.Chain.GetBlockHash(number)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Fetching balances", "accounts", len(accounts), "block", number)
+	balances, err := accountBalances(ctx, conn, metas, accounts, hash)
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeBalances(balances, format, prefix)
+	if err != nil {
+		return err
+	}
+
+	err = saveFile(path, data)
+	if err != nil {
+		return err
+	}
+
+	issuance, err := totalIssuance(ctx, conn, metas, hash)
+	if err != nil {
+		return err
+	}
+
+	total := new(big.Int)
+	for _, b := range balances {
+		total.Add(total, b.Total())
+	}
+
+	logger.Info("Saved balances", "path", path, "with_balance", len(balances), "without_balance", len(accounts)-len(balances),
+		"total", total.String(), "issuance", issuance.String(), "sha256", checksum(data))
+	switch total.Cmp(issuance) {
+	case 1:
+		return fmt.Errorf("balances total %s exceeds total issuance %s", total.String(), issuance.String())
+	case -1:
+		logger.Warn("Accounts missing from the list hold part of the issuance", "missing", new(big.Int).Sub(issuance, total).String())
+	}
+
+	return nil
+}
+
+// balanceRecord is the JSON encoding of an AccountBalance
+type balanceRecord struct {
+	Account    string `json:"account"`
+	SS58       string `json:"ss58"`
+	Nonce      uint32 `json:"nonce"`
+	Free       string `json:"free"`
+	Reserved   string `json:"reserved"`
+	MiscFrozen string `json:"misc_frozen"`
+	FeeFrozen  string `json:"fee_frozen"`
+}
+
+// balancesFormatFor returns the format balances are written to path in like formatFor,
+// failing for the formats encodeBalances does not support
+func balancesFormatFor(path string, format Format) (Format, error) {
+	f, err := formatFor(path, format)
+	if err != nil {
+		return "", err
+	}
+
+	switch f {
+	case FormatSCALE, FormatJSON, FormatCSV:
+		return f, nil
+	}
+
+	return "", fmt.Errorf("balances cannot be written as %s, use scale, json or csv", f)
+}
+
+// encodeBalances encodes the balances in the given format. FormatJSON and FormatCSV hold every field,
+// FormatSCALE is a Vec<(AccountId, Balance)> of the total balance as used in genesis configs.
+func encodeBalances(balances []AccountBalance, format Format, prefix uint16) ([]byte, error) {
+	sort.Slice(balances, func(i, j int) bool {
+		return bytes.Compare(balances[i].Account[:], balances[j].Account[:]) < 0
+	})
+
+	var buffer = bytes.Buffer{}
+	switch format {
+	case FormatSCALE:
+		encoder := scale.NewEncoder(&buffer)
+		err := encoder.EncodeUintCompact(*big.NewInt(int64(len(balances))))
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range balances {
+			err = encoder.Encode(b.Account)
+			if err != nil {
+				return nil, err
+			}
+			err = encoder.Encode(types.NewU128(*b.Total()))
+			if err != nil {
+				return nil, err
+			}
+		}
+	case FormatJSON:
+		records := make([]balanceRecord, 0, len(balances))
+		for _, b := range balances {
+			address, err := SS58Encode(b.Account, prefix)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, balanceRecord{
+				Account:    hexutil.Encode(b.Account[:]),
+				SS58:       address,
+				Nonce:      b.Nonce,
+				Free:       b.Free.String(),
+				Reserved:   b.Reserved.String(),
+				MiscFrozen: b.MiscFrozen.String(),
+				FeeFrozen:  b.FeeFrozen.String(),
+			})
+		}
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(data)
+		buffer.WriteString("\n")
+	case FormatCSV:
+		w := csv.NewWriter(&buffer)
+		err := w.Write([]string{"account_id", "ss58", "nonce", "free", "reserved", "misc_frozen", "fee_frozen"})
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range balances {
+			address, err := SS58Encode(b.Account, prefix)
+			if err != nil {
+				return nil, err
+			}
+			err = w.Write([]string{
+				hexutil.Encode(b.Account[:]),
+				address,
+				strconv.FormatUint(uint64(b.Nonce), 10),
+				b.Free.String(),
+				b.Reserved.String(),
+				b.MiscFrozen.String(),
+				b.FeeFrozen.String(),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		w.Flush()
+		if w.Error() != nil {
+			return nil, w.Error()
+		}
+	default:
+		return nil, fmt.Errorf("balances cannot be written as %s, use scale, json or csv", format)
+	}
+
+	return buffer.Bytes(), nil
+}
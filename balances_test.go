@@ -0,0 +1,126 @@
+package account_scraper
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/scale"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+// accountInfo encodes a System.Account entry with the given number of counter bytes
+func accountInfo(t *testing.T, nonce uint32, counters int, free, reserved, miscFrozen, feeFrozen int64) []byte {
+	var buf bytes.Buffer
+	encoder := scale.NewEncoder(&buf)
+	err := encoder.Encode(types.U32(nonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(bytes.Repeat([]byte{0xee}, counters))
+	for _, v := range []int64{free, reserved, miscFrozen, feeFrozen} {
+		err = encoder.Encode(types.NewU128(*big.NewInt(v)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecodeAccountInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		counters int
+	}{
+		{"u8 refcount", 1},
+		{"u32 refcount", 4},
+		{"consumers and providers", 8},
+		{"consumers, providers and sufficients", 12},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := decodeAccountInfo(alice, accountInfo(t, 7, test.counters, 1000, 200, 30, 4))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Account != alice || b.Nonce != 7 || b.Free.Int64() != 1000 || b.Reserved.Int64() != 200 ||
+				b.MiscFrozen.Int64() != 30 || b.FeeFrozen.Int64() != 4 {
+				t.Errorf("decoded %+v", b)
+			}
+			if b.Total().Int64() != 1200 {
+				t.Errorf("total %s, want 1200", b.Total())
+			}
+		})
+	}
+
+	for _, counters := range []int{0, 2, 16} {
+		if _, err := decodeAccountInfo(alice, accountInfo(t, 7, counters, 1, 2, 3, 4)); err == nil {
+			t.Errorf("decoding an entry with %d counter bytes succeeded", counters)
+		}
+	}
+}
+
+func TestEncodeBalances(t *testing.T) {
+	var other types.AccountID
+	other[0] = 0xff
+	balances := []AccountBalance{
+		{Account: other, Nonce: 1, Free: big.NewInt(5), Reserved: big.NewInt(0), MiscFrozen: big.NewInt(0), FeeFrozen: big.NewInt(0)},
+		{Account: alice, Nonce: 2, Free: big.NewInt(10), Reserved: big.NewInt(1), MiscFrozen: big.NewInt(0), FeeFrozen: big.NewInt(0)},
+	}
+
+	data, err := encodeBalances(balances, FormatSCALE, CentrifugePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []struct {
+		Account types.AccountID
+		Balance types.U128
+	}
+	err = types.DecodeFromBytes(data, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0].Account != alice || decoded[0].Balance.Int64() != 11 ||
+		decoded[1].Account != other || decoded[1].Balance.Int64() != 5 {
+		t.Errorf("decoded %+v, want the sorted accounts with their total balance", decoded)
+	}
+
+	data, err = encodeBalances(balances, FormatCSV, CentrifugePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "0xd43593c7") || !strings.HasSuffix(lines[1], ",2,10,1,0,0") {
+		t.Errorf("encoded %q", data)
+	}
+
+	if _, err := encodeBalances(balances, FormatSS58, CentrifugePrefix); err == nil {
+		t.Error("encoding balances as SS58 succeeded")
+	}
+}
+
+func TestBalancesFormatFor(t *testing.T) {
+	tests := []struct {
+		path   string
+		format Format
+		want   Format
+		err    bool
+	}{
+		{"build/balances.scale", "", FormatSCALE, false},
+		{"build/balances.csv", "", FormatCSV, false},
+		{"build/balances.csv", "json", FormatJSON, false},
+		{"build/balances.txt", "", "", true},
+		{"build/balances.json", "provenance", "", true},
+		{"build/balances.json", "jsn", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := balancesFormatFor(test.path, test.format)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("balancesFormatFor(%s, %q) = %s, %v, want %s and error %t", test.path, test.format, got, err, test.want, test.err)
+		}
+	}
+}
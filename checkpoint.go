@@ -0,0 +1,97 @@
+package account_scraper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// DefaultCheckpointPath is where the progress of a run is stored unless configured otherwise
+const DefaultCheckpointPath = "build/checkpoint.json"
+
+// checkpoint records how far a run got, so an interrupted run can be resumed
+type checkpoint struct {
+	// URL is the node the run scraped, resuming requires the same one
+	URL       string          `json:"url"`
+	LastBlock uint64          `json:"last_block"`
+	LastHash  string          `json:"last_hash"`
+	Accounts  []AccountRecord `json:"accounts"`
+	// Failures are the blocks which could not be decoded so far
+	Failures []DecodeFailure `json:"failures"`
+	// Killed are the hex encoded accounts reaped after their last account event, with the block of the kill
+	Killed map[string]uint64 `json:"killed"`
+}
+
+func saveCheckpoint(path string, cp checkpoint, found *findings) error {
+	var err error
+	cp.Accounts, err = found.accounts.records(CentrifugePrefix)
+	if err != nil {
+		return err
+	}
+	cp.Failures = found.failures
+	cp.Killed = make(map[string]uint64, len(found.killed))
+	for acc, number := range found.killed {
+		cp.Killed[hexutil.Encode(acc[:])] = number
+	}
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so a crash never leaves a truncated checkpoint behind
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func loadCheckpoint(path string) (checkpoint, *findings, error) {
+	var cp checkpoint
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cp, nil, err
+	}
+
+	err = json.Unmarshal(data, &cp)
+	if err != nil {
+		return cp, nil, err
+	}
+
+	found := newFindings()
+	err = found.accounts.addRecords(cp.Accounts)
+	if err != nil {
+		return cp, nil, errors.Wrap(err, "invalid account in checkpoint")
+	}
+	found.failures = cp.Failures
+	for elem, number := range cp.Killed {
+		acc, err := ParseAccount(elem)
+		if err != nil {
+			return cp, nil, errors.Wrap(err, "invalid killed account in checkpoint")
+		}
+		found.kill(acc, number)
+	}
+
+	return cp, found, nil
+}
+
+func removeCheckpoint(path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package account_scraper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "build", "checkpoint.json")
+
+	index := uint32(1)
+	var bob types.AccountID
+	bob[0] = 2
+
+	found := newFindings()
+	found.accounts.add(alice, Provenance{Source: "Balances.Endowed", BlockNumber: 7, BlockHash: "0x07", ExtrinsicIndex: &index, Amount: "500"})
+	found.accounts.add(bob, Provenance{Source: "genesis", Set: "genesis-amber"})
+	found.failures = []DecodeFailure{{BlockNumber: 9, BlockHash: "0x09", Error: "unknown event", RawEvents: "0x0400"}}
+	found.kill(bob, 11)
+
+	want := checkpoint{URL: "ws://127.0.0.1:9944", LastBlock: 12, LastHash: "0x0c"}
+	err = saveCheckpoint(path, want, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	cp, loaded, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.URL != want.URL || cp.LastBlock != want.LastBlock || cp.LastHash != want.LastHash {
+		t.Errorf("loaded checkpoint %s %d %s, want %s %d %s", cp.URL, cp.LastBlock, cp.LastHash, want.URL, want.LastBlock, want.LastHash)
+	}
+	if !reflect.DeepEqual(loaded.accounts, found.accounts) {
+		t.Errorf("loaded accounts %+v, want %+v", loaded.accounts, found.accounts)
+	}
+	if !reflect.DeepEqual(loaded.failures, found.failures) {
+		t.Errorf("loaded failures %+v, want %+v", loaded.failures, found.failures)
+	}
+	if !reflect.DeepEqual(loaded.killed, found.killed) {
+		t.Errorf("loaded killed %v, want %v", loaded.killed, found.killed)
+	}
+
+	err = removeCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadCheckpoint(path); !os.IsNotExist(err) {
+		t.Errorf("got error %v loading a removed checkpoint, want not exist", err)
+	}
+}
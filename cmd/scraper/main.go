@@ -1,36 +1,606 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	stdlog "log"
+	"math/big"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	as "github.com/centrifuge/account-scraper"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
 )
 
 func main() {
 	app := &cli.App{
 		Name: "Centrifuge Chain Account Scraper",
-		Description: "The scraper returns an scale encoded list of accountIDs file in out/accounts.scale",
+		Description: "The scraper returns an encoded list of accountIDs file in build/accounts.scale unless configured otherwise",
 		Usage: "requires URL of full archive node",
-		Flags: []cli.Flag {
-			&cli.StringFlag{
-				Name: "url",
-				Value: "",
-				Usage: "URL of full archive node",
+		Flags: append(scrapeFlags(), logFlags()...),
+		Before: setupLogging,
+		Action: scrape,
+	}
+
+	app.Commands = []*cli.Command{
+		{
+			Name: "scrape",
+			Usage: "collects the accounts from the events of a block range, the default command",
+			Flags: scrapeFlags(),
+			Action: scrape,
+		},
+		{
+			Name: "snapshot",
+			Usage: "writes every account in System.Account storage at a block with its balances",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name: "url",
+					Value: "",
+					Usage: "URL of full archive node",
+				},
+				&cli.StringFlag{
+					Name: "at",
+					Value: as.BlockFinalized,
+					Usage: "Block to take the snapshot at: number, hash, \"finalized\" or \"latest\"",
+				},
+				&cli.StringFlag{
+					Name: "output",
+					Value: as.DefaultSnapshotPath,
+					Usage: "File the balances are written to",
+				},
+				formatFlag("Format of the output", balancesFormats),
+				&cli.StringFlag{
+					Name: "accounts",
+					Value: "",
+					Usage: "Also writes the plain account list to this file, to diff it against a scraped one",
+				},
+				ss58PrefixFlag(),
+				maxAttemptsFlag(),
+				backoffFlag(),
+			},
+			Action: func(c *cli.Context) error {
+				prefix, err := ss58Prefix(c)
+				if err != nil {
+					return err
+				}
+
+				ctx, cancel := handleSignals(nil)
+				defer cancel()
+
+				return as.Snapshot(ctx, as.SnapshotOptions{
+					URL:         c.String("url"),
+					At:          c.String("at"),
+					Output:      c.String("output"),
+					Format:      as.Format(c.String("format")),
+					Accounts:    c.String("accounts"),
+					SS58Prefix:  &prefix,
+					MaxAttempts: c.Int("max-attempts"),
+					Backoff:     c.Duration("backoff"),
+				})
+			},
+		},
+		{
+			Name: "merge",
+			Usage: "writes the union of several account lists",
+			ArgsUsage: "<file>...",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name: "output",
+					Value: as.DefaultOutputPath,
+					Usage: "File the merged account list is written to",
+				},
+				formatFlag("Format of the output", listFormats),
+				ss58PrefixFlag(),
+			},
+			Action: func(c *cli.Context) error {
+				prefix, err := ss58Prefix(c)
+				if err != nil {
+					return err
+				}
+
+				var inputs []as.AccountFile
+				for _, path := range c.Args().Slice() {
+					inputs = append(inputs, as.AccountFile{Path: path})
+				}
+
+				return as.Merge(inputs, as.AccountFile{Path: c.String("output"), Format: as.Format(c.String("format"))}, prefix)
+			},
+		},
+		{
+			Name: "diff",
+			Usage: "reports the accounts added, removed and unchanged between two account lists, fails on unexpected removals",
+			ArgsUsage: "<base> <target>",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name: "expected-removals",
+					Value: "",
+					Usage: "Account list of the accounts allowed to be missing from the target",
+				},
+				&cli.BoolFlag{
+					Name: "allow-removals",
+					Usage: "Reports removed accounts without failing",
+				},
+				&cli.BoolFlag{
+					Name: "json",
+					Usage: "Writes the report as JSON",
+				},
+				&cli.StringFlag{
+					Name: "output",
+					Value: "",
+					Usage: "File the report is written to (defaults to stdout)",
+				},
+				ss58PrefixFlag(),
+			},
+			Action: func(c *cli.Context) error {
+				prefix, err := ss58Prefix(c)
+				if err != nil {
+					return err
+				}
+				if c.NArg() != 2 {
+					return fmt.Errorf("diff takes two account lists, got %d", c.NArg())
+				}
+
+				var expected *as.AccountFile
+				if c.String("expected-removals") != "" {
+					expected = &as.AccountFile{Path: c.String("expected-removals")}
+				}
+
+				_, err = as.Diff(os.Stdout, as.DiffOptions{
+					Base:             as.AccountFile{Path: c.Args().Get(0)},
+					Target:           as.AccountFile{Path: c.Args().Get(1)},
+					ExpectedRemovals: expected,
+					AllowRemovals:    c.Bool("allow-removals"),
+					JSON:             c.Bool("json"),
+					Output:           c.String("output"),
+					SS58Prefix:       &prefix,
+				})
+				return err
+			},
+		},
+		{
+			Name: "inspect",
+			Usage: "prints the accounts of an account list with their count and the checksum of the file",
+			ArgsUsage: "<file>",
+			Flags: []cli.Flag{
+				formatFlag("Format of the file", listFormats),
+				ss58PrefixFlag(),
 			},
-			&cli.BoolFlag{
-				Name: "append",
-				Usage: "Appends to existing scale encoded file removing duplicates",
+			Action: func(c *cli.Context) error {
+				prefix, err := ss58Prefix(c)
+				if err != nil {
+					return err
+				}
+				if c.NArg() != 1 {
+					return fmt.Errorf("inspect takes one account list, got %d", c.NArg())
+				}
+
+				return as.Inspect(os.Stdout, as.AccountFile{Path: c.Args().First(), Format: as.Format(c.String("format"))}, prefix)
 			},
 		},
-		Action: func(c *cli.Context) error {
-			return as.Process(c.String("url"), c.Bool("append"))
+		{
+			Name: "convert",
+			Usage: "writes an account list in another format",
+			ArgsUsage: "<input> <output>",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name: "input-format",
+					Value: "",
+					Usage: "Format of the input: scale, json, ss58, csv or provenance (defaults to the input file extension)",
+				},
+				formatFlag("Format of the output", listFormats),
+				ss58PrefixFlag(),
+			},
+			Action: func(c *cli.Context) error {
+				prefix, err := ss58Prefix(c)
+				if err != nil {
+					return err
+				}
+				if c.NArg() != 2 {
+					return fmt.Errorf("convert takes an input and an output file, got %d arguments", c.NArg())
+				}
+
+				return as.Convert(
+					as.AccountFile{Path: c.Args().Get(0), Format: as.Format(c.String("input-format"))},
+					as.AccountFile{Path: c.Args().Get(1), Format: as.Format(c.String("format"))},
+					prefix,
+				)
+			},
 		},
 	}
 
+	for _, cmd := range app.Commands {
+		cmd.Flags = append(cmd.Flags, logFlags()...)
+		cmd.Before = setupLogging
+	}
+
+	// diagnostics go to stderr until the flags configure them, stdout is left to account lists
+	log.Root().SetHandler(log.LvlFilterHandler(log.LvlInfo, log.StreamHandler(diagnostics, log.LogfmtFormat())))
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(stdlogWriter{})
+
 	err := app.Run(os.Args)
 	if err != nil {
-		log.Fatal(err)
+		log.Error("Failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+// logFlags are the flags configuring diagnostics, accepted by every command
+func logFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name: "log-level",
+			Value: "info",
+			Usage: "Lowest level of diagnostics written: trace, debug, info, warn, error or crit",
+		},
+		&cli.StringFlag{
+			Name: "log-format",
+			Value: "logfmt",
+			Usage: "Format of diagnostics: logfmt, json or terminal",
+		},
+	}
+}
+
+// lookup returns the value of a flag set on the command or any parent, as log flags are accepted before
+// and after the command name
+func lookup(c *cli.Context, name string) string {
+	for _, ctx := range c.Lineage() {
+		if ctx.IsSet(name) {
+			return ctx.String(name)
+		}
+	}
+
+	return c.String(name)
+}
+
+// setupLogging writes diagnostics to stderr at the configured level and format
+func setupLogging(c *cli.Context) error {
+	level, err := log.LvlFromString(lookup(c, "log-level"))
+	if err != nil {
+		return err
+	}
+
+	var format log.Format
+	switch lookup(c, "log-format") {
+	case "logfmt":
+		format = log.LogfmtFormat()
+	case "json":
+		format = log.JSONFormat()
+	case "terminal":
+		format = log.TerminalFormat(false)
+	default:
+		return fmt.Errorf("unknown log format %s, use logfmt, json or terminal", lookup(c, "log-format"))
+	}
+
+	log.Root().SetHandler(log.LvlFilterHandler(level, log.StreamHandler(diagnostics, format)))
+	return nil
+}
+
+// stdlogWriter passes the lines of the standard logger, which the RPC client writes to, on to the structured logger
+type stdlogWriter struct{}
+
+func (stdlogWriter) Write(p []byte) (int, error) {
+	log.Info(strings.TrimSpace(string(p)))
+	return len(p), nil
+}
+
+// ss58PrefixFlag is the --ss58-prefix flag shared by the commands printing or writing SS58 addresses
+func ss58PrefixFlag() cli.Flag {
+	return &cli.UintFlag{
+		Name: "ss58-prefix",
+		Value: uint(as.CentrifugePrefix),
+		Usage: "Network prefix of SS58 addresses in the output",
+	}
+}
+
+// ss58Prefix returns the value of the --ss58-prefix flag
+func ss58Prefix(c *cli.Context) (uint16, error) {
+	err := as.CheckSS58Prefix(c.Uint("ss58-prefix"))
+	if err != nil {
+		return 0, err
 	}
+
+	return uint16(c.Uint("ss58-prefix")), nil
+}
+
+// maxAttemptsFlag is the --max-attempts flag of the commands querying a node
+func maxAttemptsFlag() cli.Flag {
+	return &cli.IntFlag{
+		Name: "max-attempts",
+		Value: as.DefaultMaxAttempts,
+		Usage: "Number of tries per RPC call on transient errors",
+	}
+}
+
+// backoffFlag is the --backoff flag of the commands querying a node
+func backoffFlag() cli.Flag {
+	return &cli.DurationFlag{
+		Name: "backoff",
+		Value: as.DefaultBackoff,
+		Usage: "Wait before the first retry, doubled after every further failure",
+	}
+}
+
+const (
+	// listFormats are the formats of account lists
+	listFormats = "scale, json, ss58, csv or provenance"
+	// balancesFormats are the formats of balances
+	balancesFormats = "scale, json or csv"
+)
+
+// formatFlag is the --format flag of a file written in one of formats
+func formatFlag(usage, formats string) cli.Flag {
+	return &cli.StringFlag{
+		Name: "format",
+		Value: "",
+		Usage: usage + ": " + formats + " (defaults to the file extension)",
+	}
+}
+
+// scrapeFlags are the flags of the scrape command, also accepted without command name
+func scrapeFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name: "url",
+			Value: "",
+			Usage: "URL of full archive node",
+		},
+		&cli.BoolFlag{
+			Name: "append",
+			Usage: "Appends to existing scale encoded file removing duplicates",
+		},
+		&cli.StringFlag{
+			Name: "from",
+			Value: "",
+			Usage: "First block to process: number, hash, \"finalized\" or \"latest\" (defaults to genesis)",
+		},
+		&cli.StringFlag{
+			Name: "to",
+			Value: as.BlockFinalized,
+			Usage: "Last block to process: number, hash, \"finalized\" or \"latest\"",
+		},
+		&cli.Uint64Flag{
+			Name: "confirmations",
+			Value: 0,
+			Usage: "Number of blocks the last processed block stays behind a \"finalized\" or \"latest\" head, also when following",
+		},
+		&cli.StringFlag{
+			Name: "checkpoint",
+			Value: as.DefaultCheckpointPath,
+			Usage: "File the progress is recorded to after every processed range",
+		},
+		&cli.BoolFlag{
+			Name: "resume",
+			Usage: "Resumes an interrupted run from the checkpoint file",
+		},
+		&cli.IntFlag{
+			Name: "workers",
+			Value: 1,
+			Usage: "Number of block ranges processed in parallel",
+		},
+		&cli.StringSliceFlag{
+			Name: "account-sets",
+			Value: cli.NewStringSlice(as.DefaultAccountSetsPath),
+			Usage: "YAML, JSON or TOML files with named account sets added to the result",
+		},
+		&cli.StringSliceFlag{
+			Name: "include-set",
+			Usage: "Adds only the account sets matching a set name (e.g. genesis-main), kind (genesis) or network (main)",
+		},
+		&cli.StringSliceFlag{
+			Name: "exclude-set",
+			Usage: "Skips the account sets matching a set name, kind or network",
+		},
+		&cli.BoolFlag{
+			Name: "genesis-state",
+			Usage: "Adds the accounts in System.Account storage of the genesis block",
+		},
+		&cli.StringSliceFlag{
+			Name: "events",
+			Usage: "Events accounts are extracted from, one of " + strings.Join(as.Extractors(), ", ") + " (defaults to all)",
+		},
+		&cli.Uint64Flag{
+			Name: "step",
+			Value: as.DefaultStep,
+			Usage: "Initial number of blocks queried at once, adapted to the node's responses",
+		},
+		maxAttemptsFlag(),
+		backoffFlag(),
+		&cli.StringFlag{
+			Name: "output",
+			Value: as.DefaultOutputPath,
+			Usage: "File the account list is written to",
+		},
+		formatFlag("Format of the output", listFormats),
+		ss58PrefixFlag(),
+		&cli.StringFlag{
+			Name: "balances",
+			Value: "",
+			Usage: "Also writes the balances of the scraped accounts to this file, checked against the total issuance",
+		},
+		&cli.StringFlag{
+			Name: "balances-format",
+			Value: "",
+			Usage: "Format of the balances: scale (Vec<(AccountId, Balance)>), json or csv (defaults to the file extension)",
+		},
+		&cli.StringFlag{
+			Name: "balances-at",
+			Value: "",
+			Usage: "Block to read balances and verify accounts at: number, hash, \"finalized\" or \"latest\" (defaults to the last processed block)",
+		},
+		&cli.BoolFlag{
+			Name: "only-live",
+			Usage: "Drops accounts reaped during the scan or without System.Account entry at the balances block",
+		},
+		&cli.StringFlag{
+			Name: "min-balance",
+			Value: "",
+			Usage: "Drops accounts whose free and reserved balance at the balances block is below this amount (in the smallest unit)",
+		},
+		&cli.BoolFlag{
+			Name: "follow",
+			Usage: "Keeps processing blocks as they are finalized after reaching --to, writing the output periodically",
+		},
+		&cli.DurationFlag{
+			Name: "flush-interval",
+			Value: as.DefaultFlushInterval,
+			Usage: "How often the output is written while following the chain",
+		},
+		&cli.StringFlag{
+			Name: "input",
+			Value: "",
+			Usage: "Account list to append to (defaults to the output file)",
+		},
+		&cli.StringFlag{
+			Name: "report",
+			Value: "",
+			Usage: "File the blocks whose events failed to decode are listed in (defaults to a file next to the output)",
+		},
+		&cli.BoolFlag{
+			Name: "strict",
+			Usage: "Exits with an error when the events of any block failed to decode",
+		},
+		&cli.StringFlag{
+			Name: "progress",
+			Value: progressAuto,
+			Usage: "Progress reporting: bar, log lines, none or auto (a bar when stderr is a terminal, log lines otherwise)",
+		},
+		&cli.DurationFlag{
+			Name: "progress-interval",
+			Value: 30 * time.Second,
+			Usage: "How often progress is logged when not shown as a bar",
+		},
+	}
+}
+
+// scrape collects the accounts of a block range
+func scrape(c *cli.Context) error {
+	prefix, err := ss58Prefix(c)
+	if err != nil {
+		return err
+	}
+
+	var minBalance *big.Int
+	if c.String("min-balance") != "" {
+		var ok bool
+		minBalance, ok = new(big.Int).SetString(c.String("min-balance"), 10)
+		if !ok {
+			return fmt.Errorf("invalid minimum balance %s", c.String("min-balance"))
+		}
+	}
+
+	progress, err := newProgressReporter(c.String("progress"), c.Duration("progress-interval"))
+	if err != nil {
+		return err
+	}
+	var onProgress func(p as.Progress)
+	if progress != nil {
+		onProgress = progress.report
+	}
+
+	scraper := as.NewScraper(as.Options{
+		URL:    c.String("url"),
+		Append: c.Bool("append"),
+		From:   c.String("from"),
+		To:     c.String("to"),
+		Confirmations: c.Uint64("confirmations"),
+		Checkpoint: c.String("checkpoint"),
+		Resume: c.Bool("resume"),
+		Workers: c.Int("workers"),
+		Step: c.Uint64("step"),
+		Events: c.StringSlice("events"),
+		AccountSets: c.StringSlice("account-sets"),
+		IncludeSets: c.StringSlice("include-set"),
+		ExcludeSets: c.StringSlice("exclude-set"),
+		GenesisState: c.Bool("genesis-state"),
+		MaxAttempts: c.Int("max-attempts"),
+		Backoff: c.Duration("backoff"),
+		Output: c.String("output"),
+		Format: as.Format(c.String("format")),
+		Input: c.String("input"),
+		SS58Prefix: &prefix,
+		Balances: c.String("balances"),
+		BalancesFormat: as.Format(c.String("balances-format")),
+		BalancesAt: c.String("balances-at"),
+		OnlyLive: c.Bool("only-live"),
+		MinBalance: minBalance,
+		Follow: c.Bool("follow"),
+		FlushInterval: c.Duration("flush-interval"),
+		Report: c.String("report"),
+		Strict: c.Bool("strict"),
+		OnProgress: onProgress,
+		OnAccount: func(acc types.AccountID, p as.Provenance) {
+			// the prefix was checked by ss58Prefix
+			address, _ := as.SS58Encode(acc, prefix)
+			log.Debug("Found account", "account", hexutil.Encode(acc[:]), "ss58", address,
+				"source", p.Source, "block", p.BlockNumber)
+		},
+	})
+
+	ctx, cancel := handleSignals(scraper.Stop)
+	defer cancel()
+
+	result, err := scraper.Run(ctx)
+	if progress != nil {
+		progress.finish()
+	}
+	if errors.Cause(err) == as.ErrInterrupted {
+		log.Warn("Saved accounts found so far, continue with --resume", "block", result.LastBlock, "path", result.Output,
+			"accounts", len(result.Accounts), "sha256", result.Checksum)
+		return err
+	}
+	if err != nil && errors.Cause(err) != as.ErrDecodeFailures {
+		return err
+	}
+
+	for _, acc := range result.Accounts.Sorted() {
+		address, err := as.SS58Encode(acc, prefix)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%x %s\n", acc, address)
+	}
+	log.Info("Saved accounts", "path", result.Output, "accounts", len(result.Accounts), "sha256", result.Checksum)
+	if len(result.Failures) > 0 {
+		log.Warn("Failed to decode events of some blocks", "blocks", len(result.Failures), "report", result.Report)
+	}
+
+	return err
+}
+
+// handleSignals returns a context cancelled on SIGINT or SIGTERM. With stop given, the first signal calls it
+// to end gracefully and only a second one cancels the context.
+func handleSignals(stop func()) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		defer signal.Stop(signals)
+		if stop != nil {
+			select {
+			case <-signals:
+				log.Warn("Stopping after the current block ranges, interrupt again to abort")
+				stop()
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-signals:
+			log.Warn("Aborting")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
 }
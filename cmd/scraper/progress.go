@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	as "github.com/centrifuge/account-scraper"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// values of the --progress flag
+const (
+	progressAuto = "auto"
+	progressBar  = "bar"
+	progressLog  = "log"
+	progressNone = "none"
+)
+
+// barWidth is the number of characters of the progress bar between its brackets
+const barWidth = 30
+
+// terminal writes diagnostics to stderr while keeping a status line, the progress bar, below them
+type terminal struct {
+	mu     sync.Mutex
+	out    io.Writer
+	status string
+}
+
+// diagnostics is where log lines are written to
+var diagnostics = &terminal{out: os.Stderr}
+
+// Write clears the status line, writes p and draws the status line again
+func (t *terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status != "" {
+		fmt.Fprint(t.out, "\r\033[K")
+	}
+	n, err := t.out.Write(p)
+	if t.status != "" {
+		fmt.Fprint(t.out, t.status)
+	}
+
+	return n, err
+}
+
+// setStatus replaces the status line
+func (t *terminal) setStatus(status string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fmt.Fprint(t.out, "\r\033[K"+status)
+	t.status = status
+}
+
+// endStatus leaves the last status line in place and moves on to the next line
+func (t *terminal) endStatus() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status != "" {
+		fmt.Fprintln(t.out)
+		t.status = ""
+	}
+}
+
+// interactive reports whether stderr is a terminal
+func interactive() bool {
+	fi, err := os.Stderr.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// progressReporter renders the progress of a run as a bar or as log lines every interval
+type progressReporter struct {
+	bar      bool
+	interval time.Duration
+	logged   time.Duration
+}
+
+// newProgressReporter returns the reporter for a --progress mode, nil for progressNone
+func newProgressReporter(mode string, interval time.Duration) (*progressReporter, error) {
+	switch mode {
+	case progressAuto:
+		return &progressReporter{bar: interactive(), interval: interval}, nil
+	case progressBar:
+		return &progressReporter{bar: true, interval: interval}, nil
+	case progressLog:
+		return &progressReporter{interval: interval}, nil
+	case progressNone:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unknown progress mode %s, use auto, bar, log or none", mode)
+}
+
+// report renders the progress, it is the OnProgress callback of the scraper
+func (r *progressReporter) report(p as.Progress) {
+	if r.bar {
+		diagnostics.setStatus(progressLine(p))
+		return
+	}
+
+	if p.Elapsed-r.logged < r.interval && p.Block < p.To {
+		return
+	}
+	r.logged = p.Elapsed
+	log.Info("Progress", "block", p.Block, "to", p.To, "percent", fmt.Sprintf("%.1f", p.Percent()),
+		"blocks_per_sec", fmt.Sprintf("%.1f", p.BlocksPerSecond()), "events_per_sec", fmt.Sprintf("%.1f", p.EventsPerSecond()),
+		"accounts", p.Accounts, "eta", p.ETA().Round(time.Second))
+}
+
+// finish ends the progress bar
+func (r *progressReporter) finish() {
+	if r.bar {
+		diagnostics.endStatus()
+	}
+}
+
+// progressLine renders the progress as a bar followed by the figures
+func progressLine(p as.Progress) string {
+	filled := int(p.Percent() * barWidth / 100)
+	if filled > barWidth {
+		filled = barWidth
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	if filled > 0 && filled < barWidth {
+		bar = bar[:filled-1] + ">" + bar[filled:]
+	}
+
+	return fmt.Sprintf("[%s] %5.1f%% %d/%d | %.0f blocks/s | %.0f events/s | %d accounts | ETA %s",
+		bar, p.Percent(), p.Block, p.To, p.BlocksPerSecond(), p.EventsPerSecond(), p.Accounts, p.ETA().Round(time.Second))
+}
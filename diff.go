@@ -0,0 +1,187 @@
+package account_scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnexpectedRemovals is returned by Diff when accounts of the base are missing from the target
+// without being listed as expected removals
+var ErrUnexpectedRemovals = errors.New("accounts were removed unexpectedly")
+
+// DiffOptions configures a Diff run
+type DiffOptions struct {
+	// Base is the previous account list, Target the one compared to it
+	Base   AccountFile
+	Target AccountFile
+	// ExpectedRemovals optionally lists the accounts allowed to be missing from Target, e.g. accounts reaped since
+	ExpectedRemovals *AccountFile
+	// AllowRemovals reports removals without failing
+	AllowRemovals bool
+	// JSON writes the report as JSON instead of text
+	JSON bool
+	// Output is the file the report is written to instead of the writer passed to Diff
+	Output     string
+	SS58Prefix *uint16
+}
+
+// DiffCounts are the sizes of the lists of a DiffReport
+type DiffCounts struct {
+	Base               int `json:"base"`
+	Target             int `json:"target"`
+	Added              int `json:"added"`
+	Removed            int `json:"removed"`
+	UnexpectedRemovals int `json:"unexpected_removals"`
+	Unchanged          int `json:"unchanged"`
+}
+
+// DiffReport lists the changes between two account lists. Added and unchanged accounts have
+// their provenance in the target, removed ones their provenance in the base.
+type DiffReport struct {
+	Base               string          `json:"base"`
+	Target             string          `json:"target"`
+	Counts             DiffCounts      `json:"counts"`
+	Added              []AccountRecord `json:"added"`
+	Removed            []AccountRecord `json:"removed"`
+	UnexpectedRemovals []AccountRecord `json:"unexpected_removals"`
+	Unchanged          []AccountRecord `json:"unchanged"`
+}
+
+// diffAccounts compares target to base, removals of accounts in expected are not unexpected
+func diffAccounts(base, target, expected AccountSet, prefix uint16) (DiffReport, error) {
+	added, removed, unexpected, unchanged := make(AccountSet), make(AccountSet), make(AccountSet), make(AccountSet)
+	for acc, p := range target {
+		if _, ok := base[acc]; ok {
+			unchanged[acc] = p
+		} else {
+			added[acc] = p
+		}
+	}
+	for acc, p := range base {
+		if _, ok := target[acc]; ok {
+			continue
+		}
+		removed[acc] = p
+		if _, ok := expected[acc]; !ok {
+			unexpected[acc] = p
+		}
+	}
+
+	report := DiffReport{
+		Counts: DiffCounts{
+			Base:               len(base),
+			Target:             len(target),
+			Added:              len(added),
+			Removed:            len(removed),
+			UnexpectedRemovals: len(unexpected),
+			Unchanged:          len(unchanged),
+		},
+	}
+
+	var err error
+	for _, list := range []struct {
+		set     AccountSet
+		records *[]AccountRecord
+	}{
+		{added, &report.Added},
+		{removed, &report.Removed},
+		{unexpected, &report.UnexpectedRemovals},
+		{unchanged, &report.Unchanged},
+	} {
+		*list.records, err = list.set.records(prefix)
+		if err != nil {
+			return DiffReport{}, err
+		}
+	}
+
+	return report, nil
+}
+
+// text renders the report for review, unchanged accounts are only counted
+func (r DiffReport) text() []byte {
+	var out []byte
+	for _, rec := range r.Added {
+		out = append(out, fmt.Sprintf("+ %s %s\n", rec.Account, rec.SS58)...)
+	}
+
+	unexpected := make(map[string]bool)
+	for _, rec := range r.UnexpectedRemovals {
+		unexpected[rec.Account] = true
+	}
+	for _, rec := range r.Removed {
+		mark := "-"
+		if unexpected[rec.Account] {
+			mark = "!"
+		}
+		out = append(out, fmt.Sprintf("%s %s %s\n", mark, rec.Account, rec.SS58)...)
+	}
+
+	out = append(out, fmt.Sprintf("%s: %d accounts\n%s: %d accounts\n", r.Base, r.Counts.Base, r.Target, r.Counts.Target)...)
+	out = append(out, fmt.Sprintf("%d added, %d removed (%d unexpected), %d unchanged\n",
+		r.Counts.Added, r.Counts.Removed, r.Counts.UnexpectedRemovals, r.Counts.Unchanged)...)
+
+	return out
+}
+
+// Diff writes a report of the accounts added, removed and unchanged in the target list compared to the base list
+// to w, or to Output if set. It returns ErrUnexpectedRemovals after writing the report when accounts were removed
+// which are not expected to, unless removals are allowed.
+func Diff(w io.Writer, opts DiffOptions) (DiffReport, error) {
+	prefix := CentrifugePrefix
+	if opts.SS58Prefix != nil {
+		prefix = *opts.SS58Prefix
+	}
+
+	base, err := opts.Base.load()
+	if err != nil {
+		return DiffReport{}, err
+	}
+
+	target, err := opts.Target.load()
+	if err != nil {
+		return DiffReport{}, err
+	}
+
+	expected := make(AccountSet)
+	if opts.ExpectedRemovals != nil {
+		expected, err = opts.ExpectedRemovals.load()
+		if err != nil {
+			return DiffReport{}, err
+		}
+	}
+
+	report, err := diffAccounts(base, target, expected, prefix)
+	if err != nil {
+		return DiffReport{}, err
+	}
+	report.Base = opts.Base.Path
+	report.Target = opts.Target.Path
+
+	data := report.text()
+	if opts.JSON {
+		data, err = json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return report, err
+		}
+		data = append(data, '\n')
+	}
+
+	if opts.Output == "" {
+		_, err = w.Write(data)
+	} else {
+		err = saveFile(opts.Output, data)
+	}
+	if err != nil {
+		return report, errors.Wrap(err, "Error Writing Diff")
+	}
+
+	if !opts.AllowRemovals && report.Counts.UnexpectedRemovals > 0 {
+		return report, errors.Wrapf(ErrUnexpectedRemovals, "%d of %d removed accounts", report.Counts.UnexpectedRemovals,
+			report.Counts.Removed)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,94 @@
+package account_scraper
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestDiffAccounts(t *testing.T) {
+	a, b, c, d := account(1), account(2), account(3), account(4)
+	base := AccountSet{a: {Source: "base"}, b: {Source: "base"}, c: {Source: "base"}}
+	target := AccountSet{a: {Source: "target"}, d: {Source: "target"}}
+	expected := AccountSet{b: {Source: SourceFile}}
+
+	report, err := diffAccounts(base, target, expected, CentrifugePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := DiffCounts{Base: 3, Target: 2, Added: 1, Removed: 2, UnexpectedRemovals: 1, Unchanged: 1}
+	if report.Counts != want {
+		t.Errorf("got counts %+v, want %+v", report.Counts, want)
+	}
+	if len(report.UnexpectedRemovals) != 1 || report.UnexpectedRemovals[0].Source != "base" ||
+		!strings.HasPrefix(report.UnexpectedRemovals[0].Account, "0x03") {
+		t.Errorf("got unexpected removals %+v, want c with its base provenance", report.UnexpectedRemovals)
+	}
+	if len(report.Unchanged) != 1 || report.Unchanged[0].Source != "target" {
+		t.Errorf("got unchanged %+v, want a with its target provenance", report.Unchanged)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	save := func(name string, accountSet AccountSet) AccountFile {
+		f := AccountFile{Path: filepath.Join(dir, name)}
+		_, err := f.save(accountSet, CentrifugePrefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	// the lists share no accounts, so every account of the base is removed
+	base := save("base.scale", testAccounts(3, "base"))
+	target := save("target.json", testAccounts(2, "target"))
+	reaped := save("reaped.txt", testAccounts(3, "base"))
+
+	tests := []struct {
+		name    string
+		opts    DiffOptions
+		wantErr error
+	}{
+		{"unexpected removals", DiffOptions{Base: base, Target: target}, ErrUnexpectedRemovals},
+		{"allowed removals", DiffOptions{Base: base, Target: target, AllowRemovals: true}, nil},
+		{"expected removals", DiffOptions{Base: base, Target: target, ExpectedRemovals: &reaped}, nil},
+		{"no removals", DiffOptions{Base: base, Target: base}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			_, err := Diff(&out, test.opts)
+			if errors.Cause(err) != test.wantErr {
+				t.Errorf("got error %v, want %v", err, test.wantErr)
+			}
+			if !strings.Contains(out.String(), "unchanged") {
+				t.Errorf("report %q was not written to the writer", out.String())
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	path := filepath.Join(dir, "diff.json")
+	_, err = Diff(&out, DiffOptions{Base: base, Target: target, AllowRemovals: true, JSON: true, Output: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("report written to the writer as well as to %s", path)
+	}
+	if data, err := ioutil.ReadFile(path); err != nil || !strings.Contains(string(data), `"unexpected_removals"`) {
+		t.Errorf("read %q from %s with error %v, want the JSON report", data, path, err)
+	}
+}
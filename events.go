@@ -79,6 +79,30 @@ type EventMultisigCancelled struct {
 	Topics    []types.Hash
 }
 
+// EventVestingUpdated is emitted when the amount vested has been updated.
+// First param is the account, second is the balance still locked.
+type EventVestingUpdated struct {
+	Phase    types.Phase
+	Who      types.AccountID
+	Unvested types.U128
+	Topics   []types.Hash
+}
+
+// EventVestingCompleted is emitted when an account has become fully vested.
+type EventVestingCompleted struct {
+	Phase  types.Phase
+	Who    types.AccountID
+	Topics []types.Hash
+}
+
+// EventRadClaimsClaimed is emitted when an account claimed its rewards. First param is the account, second is the amount.
+type EventRadClaimsClaimed struct {
+	Phase  types.Phase
+	Who    types.AccountID
+	Amount types.U128
+	Topics []types.Hash
+}
+
 type EventFungibleTransfer struct {
 	Phase        types.Phase
 	Destination  types.U8
@@ -208,4 +232,7 @@ type EventRecords struct {
 	MultiAccount_MultisigApproval       []EventMultisigApproval               //nolint:stylecheck,golint
 	MultiAccount_MultisigExecuted       []EventMultisigExecuted               //nolint:stylecheck,golint
 	MultiAccount_MultisigCancelled      []EventMultisigCancelled              //nolint:stylecheck,golint
+	Vesting_VestingUpdated              []EventVestingUpdated                 //nolint:stylecheck,golint
+	Vesting_VestingCompleted            []EventVestingCompleted               //nolint:stylecheck,golint
+	RadClaims_Claimed                   []EventRadClaimsClaimed               //nolint:stylecheck,golint
 }
@@ -0,0 +1,127 @@
+package account_scraper
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+// EventAccount is an account an event refers to
+type EventAccount struct {
+	Account types.AccountID
+	Phase   types.Phase
+	// Amount is the balance the event moved to the account, nil for events without one
+	Amount *big.Int
+}
+
+// AccountExtractor returns the accounts the events of a kind in a block refer to
+type AccountExtractor func(events *EventRecords) []EventAccount
+
+// namedExtractor is an AccountExtractor with the name of the event it handles
+type namedExtractor struct {
+	name    string
+	extract AccountExtractor
+}
+
+// extractorsMu guards extractors, which runs read while others register theirs
+var extractorsMu sync.RWMutex
+
+// extractors are the AccountExtractor of every event by name. ChainBridge.FungibleTransfer is not among them,
+// its recipient is an account on the destination chain. Transfers bridged to this chain show up as Balances events.
+var extractors = map[string]AccountExtractor{
+	"Balances.Endowed": func(events *EventRecords) []EventAccount {
+		var accs []EventAccount
+		for _, e := range events.Balances_Endowed {
+			accs = append(accs, EventAccount{Account: e.Who, Phase: e.Phase, Amount: e.Balance.Int})
+		}
+		return accs
+	},
+	"Balances.Transfer": func(events *EventRecords) []EventAccount {
+		var accs []EventAccount
+		for _, e := range events.Balances_Transfer {
+			accs = append(accs, EventAccount{Account: e.To, Phase: e.Phase, Amount: e.Value.Int})
+		}
+		return accs
+	},
+	"System.NewAccount": func(events *EventRecords) []EventAccount {
+		var accs []EventAccount
+		for _, e := range events.System_NewAccount {
+			accs = append(accs, EventAccount{Account: e.Who, Phase: e.Phase})
+		}
+		return accs
+	},
+	"Vesting.VestingUpdated": func(events *EventRecords) []EventAccount {
+		var accs []EventAccount
+		for _, e := range events.Vesting_VestingUpdated {
+			accs = append(accs, EventAccount{Account: e.Who, Phase: e.Phase})
+		}
+		return accs
+	},
+	"RadClaims.Claimed": func(events *EventRecords) []EventAccount {
+		var accs []EventAccount
+		for _, e := range events.RadClaims_Claimed {
+			accs = append(accs, EventAccount{Account: e.Who, Phase: e.Phase, Amount: e.Amount.Int})
+		}
+		return accs
+	},
+	"MultiAccount.NewMultiAccount": func(events *EventRecords) []EventAccount {
+		var accs []EventAccount
+		for _, e := range events.MultiAccount_NewMultiAccount {
+			accs = append(accs, EventAccount{Account: e.ID, Phase: e.Phase})
+		}
+		return accs
+	},
+}
+
+// RegisterExtractor makes an extractor available under the given event name, replacing any registered before.
+// Runs in progress keep the extractors they started with.
+func RegisterExtractor(event string, extractor AccountExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors[event] = extractor
+}
+
+// Extractors returns the names of the events accounts can be extracted from
+func Extractors() []string {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	return extractorNames()
+}
+
+// extractorNames returns the sorted names of the extractors, the caller holds extractorsMu
+func extractorNames() []string {
+	names := make([]string, 0, len(extractors))
+	for name := range extractors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// selectExtractors returns the extractors of the given events, all of them when none are given
+func selectExtractors(events []string) ([]namedExtractor, error) {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	if len(events) == 0 {
+		events = extractorNames()
+	}
+
+	var selected []namedExtractor
+	for _, name := range events {
+		name = strings.TrimSpace(name)
+		extract, ok := extractors[name]
+		if !ok {
+			return nil, fmt.Errorf("no account extractor for event %s, available are %s", name, strings.Join(extractorNames(), ", "))
+		}
+		selected = append(selected, namedExtractor{name: name, extract: extract})
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,75 @@
+package account_scraper
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+func TestSelectExtractors(t *testing.T) {
+	all, err := selectExtractors(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(Extractors()) {
+		t.Errorf("selected %d extractors by default, want all %d", len(all), len(Extractors()))
+	}
+
+	selected, err := selectExtractors([]string{" Balances.Endowed", "System.NewAccount"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 2 || selected[0].name != "Balances.Endowed" || selected[1].name != "System.NewAccount" {
+		t.Errorf("selected %v, want Balances.Endowed and System.NewAccount", selected)
+	}
+
+	if _, err := selectExtractors([]string{"ChainBridge.FungibleTransfer"}); err == nil {
+		t.Error("selected an extractor for ChainBridge.FungibleTransfer, whose recipient is on another chain")
+	}
+}
+
+func TestRegisterExtractorConcurrently(t *testing.T) {
+	const event = "Test.Registered"
+	defer func() {
+		extractorsMu.Lock()
+		delete(extractors, event)
+		extractorsMu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterExtractor(event, func(events *EventRecords) []EventAccount { return nil })
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = selectExtractors(nil)
+		}()
+	}
+	wg.Wait()
+
+	selected, err := selectExtractors([]string{event})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accs := selected[0].extract(&EventRecords{}); accs != nil {
+		t.Errorf("registered extractor returned %v", accs)
+	}
+}
+
+func TestTransferExtractor(t *testing.T) {
+	var from, to types.AccountID
+	from[0], to[0] = 1, 2
+	events := &EventRecords{EventRecords: types.EventRecords{
+		Balances_Transfer: []types.EventBalancesTransfer{{From: from, To: to, Value: types.NewU128(*big.NewInt(7))}},
+	}}
+
+	accs := extractors["Balances.Transfer"](events)
+	if len(accs) != 1 || accs[0].Account != to || accs[0].Amount.Int64() != 7 {
+		t.Errorf("extracted %+v, want the recipient with amount 7", accs)
+	}
+}
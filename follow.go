@@ -0,0 +1,139 @@
+package account_scraper
+
+import (
+	"context"
+	"time"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client"
+	"github.com/centrifuge/go-substrate-rpc-client/rpc/chain"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DefaultFlushInterval is how often the account list is written while following the chain unless configured otherwise
+const DefaultFlushInterval = time.Minute
+
+// subscribe subscribes to finalized heads, returning the API the subscription runs on
+func (s *scan) subscribe(ctx context.Context) (*chain.FinalizedHeadsSubscription, *gsrpc.SubstrateAPI, error) {
+	var sub *chain.FinalizedHeadsSubscription
+	var api *gsrpc.SubstrateAPI
+	err := s.conn.call(ctx, func(current *gsrpc.SubstrateAPI) (err error) {
+		sub, err = current.RPC.Chain.SubscribeFinalizedHeads()
+		api = current
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sub, api, nil
+}
+
+// follower processes blocks as they are finalized
+type follower struct {
+	scan *scan
+	// processed holds the blocks processed so far, the last one is where following starts
+	processed *processedBlocks
+	// confirmations is the number of blocks a finalized block must be behind the head before it is processed
+	confirmations uint64
+	found         *findings
+	done          func(r blockRange, hash types.Hash, added []types.AccountID) error
+	flush         func(at uint64) error
+}
+
+// follow processes the blocks after the last processed one as they are finalized, calling flush every interval.
+// Heads are not guaranteed to arrive for every block, so each one processes all blocks since the previous.
+// A failed subscription is renewed on a new connection. It returns nil once the scan is stopped,
+// otherwise only when ctx is done or renewing or processing fails.
+func (f *follower) follow(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sub, api, err := f.scan.subscribe(ctx)
+		if err != nil {
+			return err
+		}
+		if last, ok := f.processed.last(); ok {
+			logger.Info("Following finalized heads", "from", last.number+1)
+		}
+
+		err = f.followSubscription(ctx, sub, ticker.C)
+		sub.Unsubscribe()
+		if err != nil || f.scan.stopped() {
+			return err
+		}
+
+		err = f.scan.conn.reconnect(api)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// followSubscription processes the heads of a subscription until it fails or the scan is stopped, which are no errors
+func (f *follower) followSubscription(ctx context.Context, sub *chain.FinalizedHeadsSubscription, tick <-chan time.Time) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-f.scan.stop:
+			return nil
+		case head, ok := <-sub.Chan():
+			if !ok {
+				return nil
+			}
+
+			if uint64(head.Number) < f.confirmations {
+				continue
+			}
+			err := f.processUntil(ctx, uint64(head.Number)-f.confirmations)
+			if err != nil {
+				return err
+			}
+		case err := <-sub.Err():
+			logger.Warn("Finalized heads subscription failed", "err", err)
+			return nil
+		case <-tick:
+			last, _ := f.processed.last()
+			err := f.flush(last.number)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// processUntil processes the blocks after the last processed one up to number. When the parent of the next block
+// is not the last processed block, the accounts found after the common ancestor are rolled back and processed again.
+func (f *follower) processUntil(ctx context.Context, number uint64) error {
+	last, ok := f.processed.last()
+	if !ok || number <= last.number {
+		return nil
+	}
+
+	parent, err := parentHash(ctx, f.scan.conn, last.number+1)
+	if err != nil {
+		return err
+	}
+	if parent != last.hash {
+		ancestor, err := f.processed.rewind(func(number uint64) (types.Hash, error) {
+			return canonicalHash(ctx, f.scan.conn, number)
+		})
+		if err != nil {
+			return err
+		}
+
+		removed := f.found.rollback(ancestor.number)
+		logger.Warn("Reorg, rolling back orphaned blocks", "ancestor", ancestor.number, "accounts", len(removed))
+		for _, acc := range removed {
+			logger.Info("Rolled back account", "account", hexutil.Encode(acc[:]))
+		}
+		last = ancestor
+	}
+
+	return f.scan.processRanges(ctx, last.number+1, number, 1, f.found, f.done)
+}
@@ -0,0 +1,237 @@
+package account_scraper
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/centrifuge/go-substrate-rpc-client/scale"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Format is an encoding of an account list
+type Format string
+
+const (
+	// FormatSCALE is a SCALE encoded Vec<AccountId>
+	FormatSCALE Format = "scale"
+	// FormatJSON is a JSON array of hex encoded accounts
+	FormatJSON Format = "json"
+	// FormatSS58 is a text file with one SS58 address per line
+	FormatSS58 Format = "ss58"
+	// FormatCSV is a CSV file with the hex and SS58 encoding of an account per row
+	FormatCSV Format = "csv"
+	// FormatProvenance is a JSON array of AccountRecord, telling when and why every account was added
+	FormatProvenance Format = "provenance"
+)
+
+// DefaultOutputPath is where the account list is written unless configured otherwise
+const DefaultOutputPath = "build/accounts.scale"
+
+var formatExtensions = map[string]Format{
+	".scale": FormatSCALE,
+	".json":  FormatJSON,
+	".ss58":  FormatSS58,
+	".txt":   FormatSS58,
+	".csv":   FormatCSV,
+}
+
+// ParseFormat returns the format of the given name
+func ParseFormat(name string) (Format, error) {
+	f := Format(strings.ToLower(name))
+	switch f {
+	case FormatSCALE, FormatJSON, FormatSS58, FormatCSV, FormatProvenance:
+		return f, nil
+	}
+
+	return "", fmt.Errorf("unknown format %s", name)
+}
+
+// formatFor returns the explicitly requested format, or the one matching the extension of path.
+// Files without a known extension default to FormatSCALE.
+func formatFor(path string, format Format) (Format, error) {
+	if format != "" {
+		return ParseFormat(string(format))
+	}
+
+	if f, ok := formatExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return f, nil
+	}
+
+	return FormatSCALE, nil
+}
+
+// sortedAccounts returns the accounts of the set in ascending byte order, the canonical order of every output
+func sortedAccounts(accountSet AccountSet) []types.AccountID {
+	accounts := make([]types.AccountID, 0, len(accountSet))
+	for acc := range accountSet {
+		accounts = append(accounts, acc)
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return bytes.Compare(accounts[i][:], accounts[j][:]) < 0
+	})
+
+	return accounts
+}
+
+// checksum returns the hex encoded SHA-256 hash of an encoded account list, matching the output of sha256sum
+func checksum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// encodeAccounts encodes the accounts in canonical order in the given format, SS58 addresses using the given network prefix
+func encodeAccounts(accountSet AccountSet, format Format, prefix uint16) ([]byte, error) {
+	accounts := sortedAccounts(accountSet)
+	var buffer = bytes.Buffer{}
+	switch format {
+	case FormatSCALE:
+		err := scale.NewEncoder(&buffer).Encode(accounts)
+		if err != nil {
+			return nil, err
+		}
+	case FormatJSON:
+		list := make([]string, 0, len(accounts))
+		for _, acc := range accounts {
+			list = append(list, hexutil.Encode(acc[:]))
+		}
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(data)
+		buffer.WriteString("\n")
+	case FormatSS58:
+		for _, acc := range accounts {
+			address, err := SS58Encode(acc, prefix)
+			if err != nil {
+				return nil, err
+			}
+			buffer.WriteString(address)
+			buffer.WriteString("\n")
+		}
+	case FormatCSV:
+		w := csv.NewWriter(&buffer)
+		err := w.Write([]string{"account_id", "ss58"})
+		if err != nil {
+			return nil, err
+		}
+		for _, acc := range accounts {
+			address, err := SS58Encode(acc, prefix)
+			if err != nil {
+				return nil, err
+			}
+			err = w.Write([]string{hexutil.Encode(acc[:]), address})
+			if err != nil {
+				return nil, err
+			}
+		}
+		w.Flush()
+		if w.Error() != nil {
+			return nil, w.Error()
+		}
+	case FormatProvenance:
+		records, err := accountSet.records(prefix)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(data)
+		buffer.WriteString("\n")
+	default:
+		return nil, fmt.Errorf("unknown format %s", format)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// decodeAccounts decodes an account list in the given format.
+// Accounts in the JSON, SS58 and CSV formats may be given as hex or as SS58 address.
+// Only FormatProvenance keeps the provenance of accounts, the others are recorded as loaded from the file at path.
+func decodeAccounts(data []byte, format Format, path string) (AccountSet, error) {
+	var accounts []types.AccountID
+	switch format {
+	case FormatSCALE:
+		err := types.DecodeFromBytes(data, &accounts)
+		if err != nil {
+			return nil, err
+		}
+	case FormatJSON:
+		var list []string
+		err := json.Unmarshal(data, &list)
+		if err != nil {
+			return nil, err
+		}
+		for _, elem := range list {
+			acc, err := ParseAccount(elem)
+			if err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, acc)
+		}
+	case FormatSS58:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			acc, err := ParseAccount(line)
+			if err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, acc)
+		}
+		if scanner.Err() != nil {
+			return nil, scanner.Err()
+		}
+	case FormatCSV:
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, record := range records {
+			if i == 0 && record[0] == "account_id" {
+				continue
+			}
+			acc, err := ParseAccount(record[0])
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %s", i+1, err.Error())
+			}
+			accounts = append(accounts, acc)
+		}
+	case FormatProvenance:
+		var records []AccountRecord
+		err := json.Unmarshal(data, &records)
+		if err != nil {
+			return nil, err
+		}
+		accountSet := make(AccountSet)
+		err = accountSet.addRecords(records)
+		if err != nil {
+			return nil, err
+		}
+		return accountSet, nil
+	default:
+		return nil, fmt.Errorf("unknown format %s", format)
+	}
+
+	accountSet := make(AccountSet)
+	for _, acc := range accounts {
+		accountSet.add(acc, Provenance{Source: SourceFile, File: path})
+	}
+
+	return accountSet, nil
+}
@@ -0,0 +1,142 @@
+package account_scraper
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+// testAccounts returns a set of n distinct accounts as loaded from path
+func testAccounts(n int, path string) AccountSet {
+	accountSet := make(AccountSet)
+	for i := 0; i < n; i++ {
+		var acc types.AccountID
+		acc[0], acc[31] = byte(n-i), byte(i)
+		accountSet.add(acc, Provenance{Source: SourceFile, File: path})
+	}
+
+	return accountSet
+}
+
+func TestEncodeDecodeAccounts(t *testing.T) {
+	for _, format := range []Format{FormatSCALE, FormatJSON, FormatSS58, FormatCSV} {
+		for _, n := range []int{0, 1, 5} {
+			accountSet := testAccounts(n, "accounts")
+			data, err := encodeAccounts(accountSet, format, CentrifugePrefix)
+			if err != nil {
+				t.Fatalf("encoding %d accounts as %s: %v", n, format, err)
+			}
+
+			decoded, err := decodeAccounts(data, format, "accounts")
+			if err != nil {
+				t.Fatalf("decoding %d accounts as %s: %v", n, format, err)
+			}
+			if len(decoded) != len(accountSet) {
+				t.Fatalf("decoded %d accounts as %s, want %d", len(decoded), format, len(accountSet))
+			}
+			for acc, p := range accountSet {
+				if decoded[acc] != p {
+					t.Errorf("account %x decoded as %s with provenance %+v, want %+v", acc, format, decoded[acc], p)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeAccountsEncodings(t *testing.T) {
+	const hex = "0xd43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d"
+	const address = "5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY"
+
+	tests := []struct {
+		format Format
+		data   string
+	}{
+		{FormatJSON, `["` + hex + `", "` + address + `"]`},
+		{FormatSS58, hex + "\n\n  " + address + "\n"},
+		{FormatCSV, "account_id,ss58\n" + hex + ",x\n" + address + ",y\n"},
+		{FormatCSV, hex + "\n"},
+	}
+
+	for _, test := range tests {
+		accountSet, err := decodeAccounts([]byte(test.data), test.format, "accounts")
+		if err != nil {
+			t.Fatalf("decoding %q as %s: %v", test.data, test.format, err)
+		}
+		if _, ok := accountSet[alice]; len(accountSet) != 1 || !ok {
+			t.Errorf("decoding %q as %s got %d accounts, want alice only", test.data, test.format, len(accountSet))
+		}
+	}
+
+	if _, err := decodeAccounts([]byte("not an account\n"), FormatSS58, "accounts"); err == nil {
+		t.Error("decoding an invalid account succeeded")
+	}
+}
+
+func TestFormatFor(t *testing.T) {
+	tests := []struct {
+		path   string
+		format Format
+		want   Format
+		err    bool
+	}{
+		{"build/accounts.scale", "", FormatSCALE, false},
+		{"build/accounts", "", FormatSCALE, false},
+		{"accounts.JSON", "", FormatJSON, false},
+		{"accounts.txt", "", FormatSS58, false},
+		{"accounts.csv", "", FormatCSV, false},
+		{"accounts.json", "provenance", FormatProvenance, false},
+		{"accounts.json", "CSV", FormatCSV, false},
+		{"accounts.json", "yaml", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := formatFor(test.path, test.format)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("formatFor(%s, %q) = %s, %v, want %s and error %t", test.path, test.format, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	}
+
+	for _, test := range tests {
+		if got := checksum([]byte(test.data)); got != test.want {
+			t.Errorf("checksum(%q) = %s, want %s", test.data, got, test.want)
+		}
+	}
+}
+
+func TestEncodeAccountsCanonical(t *testing.T) {
+	accountSet := testAccounts(50, "accounts")
+	sorted := sortedAccounts(accountSet)
+	for i := 1; i < len(sorted); i++ {
+		if string(sorted[i-1][:]) >= string(sorted[i][:]) {
+			t.Fatalf("account %d %x is not after %x", i, sorted[i], sorted[i-1])
+		}
+	}
+
+	for _, format := range []Format{FormatSCALE, FormatJSON, FormatSS58, FormatCSV, FormatProvenance} {
+		first, err := encodeAccounts(accountSet, format, CentrifugePrefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// maps iterate in random order, the encoding must not depend on it
+		for i := 0; i < 5; i++ {
+			data, err := encodeAccounts(accountSet, format, CentrifugePrefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if checksum(data) != checksum(first) {
+				t.Fatalf("encoding the same accounts as %s changed the checksum", format)
+			}
+		}
+	}
+}
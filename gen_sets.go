@@ -0,0 +1,43 @@
+//go:build ignore
+// +build ignore
+
+// gen_sets writes config/accounts.yaml into sets_default.go, so the default account sets are built into the scraper.
+// Run it with go generate after changing the file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+func main() {
+	data, err := ioutil.ReadFile("config/accounts.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if strings.Contains(string(data), "`") {
+		log.Fatal("config/accounts.yaml must not contain backquotes")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by gen_sets.go from config/accounts.yaml. DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package account_scraper")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "// defaultAccountSets is the content of DefaultAccountSetsPath")
+	fmt.Fprintf(&buf, "const defaultAccountSets = `%s`\n", data)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = ioutil.WriteFile("sets_default.go", src, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
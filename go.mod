@@ -3,6 +3,7 @@ module github.com/centrifuge/account-scraper
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/centrifuge/go-substrate-rpc-client v2.0.0-alpha.5.0.20200825131151-a5b9dc6158b2+incompatible
 	github.com/deckarep/golang-set v1.7.1 // indirect
 	github.com/ethereum/go-ethereum v1.9.21
@@ -12,5 +13,6 @@ require (
 	github.com/rs/cors v1.7.0 // indirect
 	github.com/stretchr/testify v1.6.1 // indirect
 	github.com/urfave/cli/v2 v2.2.0
-	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a // indirect
+	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
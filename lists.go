@@ -0,0 +1,126 @@
+package account_scraper
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// AccountFile is an account list on disk
+type AccountFile struct {
+	Path string
+	// Format is the encoding of the file, derived from its extension when empty
+	Format Format
+}
+
+// load reads the accounts of the file
+func (f AccountFile) load() (AccountSet, error) {
+	format, err := formatFor(f.Path, f.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	accountSet, err := loadAccounts(f.Path, format)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error Loading %s", f.Path)
+	}
+
+	return accountSet, nil
+}
+
+// save writes the accounts to the file and returns the checksum of its content
+func (f AccountFile) save(accountSet AccountSet, prefix uint16) (string, error) {
+	format, err := formatFor(f.Path, f.Format)
+	if err != nil {
+		return "", err
+	}
+
+	sum, err := encodeAndSave(accountSet, f.Path, format, prefix)
+	if err != nil {
+		return "", errors.Wrapf(err, "Error Saving %s", f.Path)
+	}
+
+	return sum, nil
+}
+
+// Merge writes the union of the input account lists to output.
+// Accounts listed in several inputs keep the provenance of the first one.
+func Merge(inputs []AccountFile, output AccountFile, prefix uint16) error {
+	if len(inputs) == 0 {
+		return errors.New("nothing to merge, no input files given")
+	}
+
+	merged := make(AccountSet)
+	for _, input := range inputs {
+		accountSet, err := input.load()
+		if err != nil {
+			return err
+		}
+
+		added := 0
+		for acc, p := range accountSet {
+			if merged.add(acc, p) {
+				added++
+			}
+		}
+		logger.Info("Merged account list", "path", input.Path, "accounts", len(accountSet), "new", added)
+	}
+
+	sum, err := output.save(merged, prefix)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Saved accounts", "path", output.Path, "accounts", len(merged), "sha256", sum)
+	return nil
+}
+
+// Inspect writes the accounts of an account list to w, followed by their count and the checksum of the file
+func Inspect(w io.Writer, input AccountFile, prefix uint16) error {
+	format, err := formatFor(input.Path, input.Format)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(input.Path)
+	if err != nil {
+		return err
+	}
+
+	accountSet, err := decodeAccounts(data, format, input.Path)
+	if err != nil {
+		return errors.Wrapf(err, "Error Decoding %s", input.Path)
+	}
+
+	for _, acc := range sortedAccounts(accountSet) {
+		address, err := SS58Encode(acc, prefix)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%x %s\n", acc, address)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "%d accounts (%s), SHA-256 %s\n", len(accountSet), format, checksum(data))
+
+	return err
+}
+
+// Convert writes the accounts of input to output in the format of output
+func Convert(input, output AccountFile, prefix uint16) error {
+	accountSet, err := input.load()
+	if err != nil {
+		return err
+	}
+
+	sum, err := output.save(accountSet, prefix)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Saved accounts", "path", output.Path, "accounts", len(accountSet), "sha256", sum)
+	return nil
+}
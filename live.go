@@ -0,0 +1,72 @@
+package account_scraper
+
+import (
+	"context"
+	"math/big"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+// killedAccounts returns the accounts reaped in a block, either by System.KilledAccount
+// or by Balances.DustLost when their balance fell below the existential deposit
+func killedAccounts(events *EventRecords) []types.AccountID {
+	var accs []types.AccountID
+	for _, e := range events.System_KilledAccount {
+		accs = append(accs, e.Who)
+	}
+	for _, e := range events.Balances_DustLost {
+		accs = append(accs, e.Who)
+	}
+
+	return accs
+}
+
+// dropKilled removes the accounts whose last event was a kill from the set and returns how many it removed
+func dropKilled(accountSet AccountSet, killed map[types.AccountID]uint64) int {
+	count := 0
+	for acc := range killed {
+		if _, ok := accountSet[acc]; ok {
+			delete(accountSet, acc)
+			count++
+		}
+	}
+
+	return count
+}
+
+// dropDead removes the accounts without System.Account entry or with a total balance below minBalance
+// at the given block from the set, returning how many it removed
+func dropDead(ctx context.Context, conn *connection, metas *metadataCache, accountSet AccountSet, number uint64, minBalance *big.Int) (int, error) {
+	var hash types.Hash
+	err := conn.call(ctx, func(api *gsrpc.SubstrateAPI) (err error) {
+		hash, err = api.RPC.Chain.GetBlockHash(number)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	logger.Info("Verifying accounts exist", "accounts", len(accountSet), "block", number)
+	balances, err := accountBalances(ctx, conn, metas, sortedAccounts(accountSet), hash)
+	if err != nil {
+		return 0, err
+	}
+
+	live := make(map[types.AccountID]bool)
+	for _, b := range balances {
+		if minBalance == nil || b.Total().Cmp(minBalance) >= 0 {
+			live[b.Account] = true
+		}
+	}
+
+	count := 0
+	for acc := range accountSet {
+		if !live[acc] {
+			delete(accountSet, acc)
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,39 @@
+package account_scraper
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+func TestKilledAccounts(t *testing.T) {
+	a, b := account(1), account(2)
+	events := &EventRecords{EventRecords: types.EventRecords{
+		System_KilledAccount: []types.EventSystemKilledAccount{{Who: a}},
+		Balances_DustLost:    []types.EventBalancesDustLost{{Who: b}},
+	}}
+
+	killed := killedAccounts(events)
+	if len(killed) != 2 || killed[0] != a || killed[1] != b {
+		t.Errorf("got killed accounts %x, want %x and %x", killed, a, b)
+	}
+}
+
+func TestDropKilledKeepsFound(t *testing.T) {
+	a, b := account(1), account(2)
+	found := newFindings()
+	found.accounts.add(a, Provenance{Source: "Balances.Endowed"})
+	found.accounts.add(b, Provenance{Source: "Balances.Endowed"})
+	found.kill(b, 5)
+
+	verified := found.accounts.clone()
+	if dropped := dropKilled(verified, found.killed); dropped != 1 {
+		t.Errorf("dropped %d accounts, want 1", dropped)
+	}
+	if _, ok := verified[b]; ok || len(verified) != 1 {
+		t.Errorf("verified accounts %v still hold the killed one", verified)
+	}
+	if len(found.accounts) != 2 {
+		t.Errorf("dropping from the copy left %d accounts found, want 2", len(found.accounts))
+	}
+}
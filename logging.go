@@ -0,0 +1,14 @@
+package account_scraper
+
+import (
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// logger receives the diagnostics of the scraper. It defaults to the root logger of go-ethereum's log package,
+// which discards everything until a handler is set.
+var logger = log.Root()
+
+// SetLogger replaces the logger diagnostics are written to, call it before running anything
+func SetLogger(l log.Logger) {
+	logger = l
+}
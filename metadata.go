@@ -0,0 +1,151 @@
+package account_scraper
+
+import (
+	"sync"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+// metadataCache holds the metadata of every runtime version seen, so events of historical blocks
+// are decoded with the metadata of the runtime that emitted them
+type metadataCache struct {
+	mu     sync.Mutex
+	bySpec map[types.U32]*types.Metadata
+}
+
+func newMetadataCache() *metadataCache {
+	return &metadataCache{bySpec: make(map[types.U32]*types.Metadata)}
+}
+
+// runtimeSource is what looking up the runtime of blocks needs from the node
+type runtimeSource interface {
+	GetHeader(hash types.Hash) (*types.Header, error)
+	GetRuntimeVersion(hash types.Hash) (*types.RuntimeVersion, error)
+	GetMetadata(hash types.Hash) (*types.Metadata, error)
+}
+
+// nodeRuntime looks up runtimes through the node API
+type nodeRuntime struct {
+	api *gsrpc.SubstrateAPI
+}
+
+func (n nodeRuntime) GetHeader(hash types.Hash) (*types.Header, error) {
+	return n.api.RPC.Chain.GetHeader(hash)
+}
+
+func (n nodeRuntime) GetRuntimeVersion(hash types.Hash) (*types.RuntimeVersion, error) {
+	return n.api.RPC.State.GetRuntimeVersion(hash)
+}
+
+func (n nodeRuntime) GetMetadata(hash types.Hash) (*types.Metadata, error) {
+	return n.api.RPC.State.GetMetadata(hash)
+}
+
+// specVersion returns the spec version of the runtime at the given block
+func specVersion(src runtimeSource, hash types.Hash) (types.U32, error) {
+	rv, err := src.GetRuntimeVersion(hash)
+	if err != nil {
+		return 0, err
+	}
+
+	return rv.SpecVersion, nil
+}
+
+// executedBy returns the block whose state holds the runtime that executed the given block, which is its parent.
+// A runtime upgrade block already stores the new code, but its own events were emitted by the old runtime.
+// The genesis block has no parent and is its own.
+func executedBy(src runtimeSource, hash types.Hash) (types.Hash, error) {
+	header, err := src.GetHeader(hash)
+	if err != nil {
+		return types.Hash{}, err
+	}
+	if header.Number == 0 {
+		return hash, nil
+	}
+
+	return header.ParentHash, nil
+}
+
+// forSpec returns the metadata of the given spec version, fetching it at hash if not cached yet.
+// hash must be a block running that spec version.
+func (c *metadataCache) forSpec(src runtimeSource, spec types.U32, hash types.Hash) (*types.Metadata, error) {
+	c.mu.Lock()
+	meta, ok := c.bySpec[spec]
+	c.mu.Unlock()
+	if ok {
+		return meta, nil
+	}
+
+	meta, err := src.GetMetadata(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.bySpec[spec]; !ok {
+		logger.Debug("Fetched metadata", "spec", spec, "block", hash.Hex())
+		c.bySpec[spec] = meta
+	}
+
+	return c.bySpec[spec], nil
+}
+
+// forBlock returns the metadata of the runtime in the state of the given block, which its storage is laid out by
+func (c *metadataCache) forBlock(src runtimeSource, hash types.Hash) (*types.Metadata, error) {
+	spec, err := specVersion(src, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.forSpec(src, spec, hash)
+}
+
+// forEvents returns the metadata of the runtime that emitted the events of the given block
+func (c *metadataCache) forEvents(src runtimeSource, hash types.Hash) (*types.Metadata, error) {
+	parent, err := executedBy(src, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.forBlock(src, parent)
+}
+
+// rangeMetadata returns a lookup of the metadata to decode the events of blocks between the lower and upper hashes.
+// Runtime upgrades are rare, so when the runtimes executing both ends have the same spec version the whole range
+// shares its metadata and only ranges spanning an upgrade query the version block by block.
+func (c *metadataCache) rangeMetadata(src runtimeSource, lower, upper types.Hash) (func(types.Hash) (*types.Metadata, error), error) {
+	lowerParent, err := executedBy(src, lower)
+	if err != nil {
+		return nil, err
+	}
+
+	upperParent, err := executedBy(src, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	lowerSpec, err := specVersion(src, lowerParent)
+	if err != nil {
+		return nil, err
+	}
+
+	upperSpec, err := specVersion(src, upperParent)
+	if err != nil {
+		return nil, err
+	}
+
+	if lowerSpec == upperSpec {
+		meta, err := c.forSpec(src, upperSpec, upperParent)
+		if err != nil {
+			return nil, err
+		}
+		return func(types.Hash) (*types.Metadata, error) { return meta, nil }, nil
+	}
+
+	logger.Info("Runtime upgrade in range", "from_spec", lowerSpec, "to_spec", upperSpec, "lower", lower.Hex(), "upper", upper.Hex())
+	return func(hash types.Hash) (*types.Metadata, error) {
+		return c.forEvents(src, hash)
+	}, nil
+}
@@ -0,0 +1,111 @@
+package account_scraper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+// fakeChain is a chain whose runtime is upgraded from spec version 1 to 2 in block upgrade,
+// so the state of upgrade and every later block holds the new runtime
+type fakeChain struct {
+	upgrade uint64
+	metas   map[types.U32]*types.Metadata
+}
+
+func newFakeChain(upgrade uint64) *fakeChain {
+	return &fakeChain{upgrade: upgrade, metas: map[types.U32]*types.Metadata{1: {Version: 11}, 2: {Version: 12}}}
+}
+
+func fakeHash(number uint64) types.Hash {
+	return types.NewHash([]byte(fmt.Sprintf("%032d", number)))
+}
+
+func (c *fakeChain) number(hash types.Hash) (uint64, error) {
+	var n uint64
+	_, err := fmt.Sscanf(string(hash[:]), "%032d", &n)
+	return n, err
+}
+
+func (c *fakeChain) spec(hash types.Hash) (types.U32, error) {
+	n, err := c.number(hash)
+	if err != nil {
+		return 0, err
+	}
+	if n >= c.upgrade {
+		return 2, nil
+	}
+	return 1, nil
+}
+
+func (c *fakeChain) GetHeader(hash types.Hash) (*types.Header, error) {
+	n, err := c.number(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &types.Header{Number: types.BlockNumber(n)}
+	if n > 0 {
+		header.ParentHash = fakeHash(n - 1)
+	}
+	return header, nil
+}
+
+func (c *fakeChain) GetRuntimeVersion(hash types.Hash) (*types.RuntimeVersion, error) {
+	spec, err := c.spec(hash)
+	if err != nil {
+		return nil, err
+	}
+	return &types.RuntimeVersion{SpecVersion: spec}, nil
+}
+
+func (c *fakeChain) GetMetadata(hash types.Hash) (*types.Metadata, error) {
+	spec, err := c.spec(hash)
+	if err != nil {
+		return nil, err
+	}
+	return c.metas[spec], nil
+}
+
+func TestRangeMetadata(t *testing.T) {
+	const upgrade = 10
+	tests := []struct {
+		name         string
+		lower, upper uint64
+	}{
+		{"from genesis", 0, 3},
+		{"before upgrade", 5, 9},
+		{"ending at upgrade", 7, upgrade},
+		{"starting at upgrade", upgrade, 13},
+		{"spanning upgrade", 7, 13},
+		{"after upgrade", 11, 13},
+		{"upgrade only", upgrade, upgrade},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chain := newFakeChain(upgrade)
+			metaAt, err := newMetadataCache().rangeMetadata(chain, fakeHash(test.lower), fakeHash(test.upper))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for n := test.lower; n <= test.upper; n++ {
+				meta, err := metaAt(fakeHash(n))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// the upgrade block itself was executed by the old runtime
+				want := chain.metas[1]
+				if n > upgrade {
+					want = chain.metas[2]
+				}
+				if meta != want {
+					t.Errorf("block %d decoded with metadata version %d, want %d", n, meta.Version, want.Version)
+				}
+			}
+		})
+	}
+}
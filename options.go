@@ -0,0 +1,234 @@
+package account_scraper
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+const (
+	// BlockLatest refers to the best block known by the node
+	BlockLatest = "latest"
+	// BlockFinalized refers to the last finalized block known by the node
+	BlockFinalized = "finalized"
+)
+
+// Options configures a Scraper
+type Options struct {
+	URL    string
+	Append bool
+	// From and To bound the processed block range (both inclusive).
+	// Each accepts a block number, a block hash or one of BlockLatest and BlockFinalized.
+	// Empty From defaults to the genesis block, empty To to BlockFinalized, as blocks after it may still be reorged away.
+	From string
+	To   string
+	// Confirmations is the number of blocks To stays behind the head when it is BlockLatest or BlockFinalized,
+	// and the followed finalized heads in Follow mode
+	Confirmations uint64
+	// Checkpoint is the file progress is recorded to after every range, defaults to DefaultCheckpointPath
+	Checkpoint string
+	// Resume continues from the block and accounts recorded in Checkpoint instead of From
+	Resume bool
+	// Workers is the number of ranges fetched and decoded in parallel, defaults to 1
+	Workers int
+	// Step is the number of blocks queried at once to start with, defaults to DefaultStep.
+	// It is halved for ranges the node fails to answer and doubled while responses stay small.
+	Step uint64
+	// AccountSets are the files with named account lists (see DefaultAccountSetsPath) added to the result
+	AccountSets []string
+	// IncludeSets selects the account sets to add by set name, kind or network, all of them when empty
+	IncludeSets []string
+	// ExcludeSets removes account sets from the selection by set name, kind or network
+	ExcludeSets []string
+	// GenesisState adds every account in System.Account storage of the genesis block
+	GenesisState bool
+	// Events are the names of the events accounts are extracted from, see Extractors. Defaults to all of them.
+	Events []string
+	// MaxAttempts caps the tries per range on transient RPC errors, defaults to DefaultMaxAttempts
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled for every further one, defaults to DefaultBackoff
+	Backoff time.Duration
+	// Output is the file the account list is written to, defaults to DefaultOutputPath
+	Output string
+	// Format is the encoding of Output, derived from its extension when empty
+	Format Format
+	// Input is the account list loaded when appending, defaults to Output. Its format is derived from its extension.
+	Input string
+	// Balances optionally writes the balances of the scraped accounts to this file
+	Balances string
+	// BalancesFormat is the encoding of Balances: scale, json or csv. Derived from its extension when empty.
+	BalancesFormat Format
+	// BalancesAt is the block balances are read and accounts verified at, defaults to the last processed block
+	BalancesAt string
+	// OnlyLive drops the accounts reaped (System.KilledAccount, Balances.DustLost) after their last account event,
+	// as well as those without System.Account entry at BalancesAt
+	OnlyLive bool
+	// MinBalance drops the accounts whose free and reserved balance at BalancesAt is below it
+	MinBalance *big.Int
+	// Follow keeps processing blocks as they are finalized after reaching To, writing the account list every FlushInterval
+	Follow bool
+	// FlushInterval defaults to DefaultFlushInterval
+	FlushInterval time.Duration
+	// SS58Prefix is the network prefix of SS58 addresses in outputs, defaults to CentrifugePrefix
+	SS58Prefix *uint16
+	// Report is the file blocks whose events failed to decode are listed in, defaults to a file next to Output
+	Report string
+	// Strict makes Process fail when the events of any block could not be decoded
+	Strict bool
+	// OnAccount is called with every account found in events along with its provenance, in block order
+	// as soon as its range is processed
+	OnAccount func(acc types.AccountID, p Provenance)
+	// OnProgress is called after every processed range
+	OnProgress func(p Progress)
+}
+
+// resolveBlock turns a block reference into a block number
+func resolveBlock(api *gsrpc.SubstrateAPI, ref string) (uint64, error) {
+	ref = strings.TrimSpace(ref)
+	switch strings.ToLower(ref) {
+	case BlockLatest:
+		header, err := api.RPC.Chain.GetHeaderLatest()
+		if err != nil {
+			return 0, err
+		}
+		return uint64(header.Number), nil
+	case BlockFinalized:
+		hash, err := api.RPC.Chain.GetFinalizedHead()
+		if err != nil {
+			return 0, err
+		}
+		return blockNumber(api, hash)
+	}
+
+	if strings.HasPrefix(ref, "0x") {
+		hash, err := types.NewHashFromHexString(ref)
+		if err != nil {
+			return 0, fmt.Errorf("invalid block hash %s: %s", ref, err.Error())
+		}
+		return blockNumber(api, hash)
+	}
+
+	number, err := strconv.ParseUint(ref, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid block reference %s", ref)
+	}
+
+	return number, nil
+}
+
+// resolveBlockHash turns a block reference into a block number and hash. A hash is used as given,
+// even when the block is not canonical, other references resolve to the canonical block.
+func resolveBlockHash(api *gsrpc.SubstrateAPI, ref string) (uint64, types.Hash, error) {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, "0x") {
+		hash, err := types.NewHashFromHexString(ref)
+		if err != nil {
+			return 0, types.Hash{}, fmt.Errorf("invalid block hash %s: %s", ref, err.Error())
+		}
+		number, err := blockNumber(api, hash)
+		if err != nil {
+			return 0, types.Hash{}, err
+		}
+		return number, hash, nil
+	}
+
+	number, err := resolveBlock(api, ref)
+	if err != nil {
+		return 0, types.Hash{}, err
+	}
+	hash, err := api.RPC.Chain.GetBlockHash(number)
+	if err != nil {
+		return 0, types.Hash{}, err
+	}
+
+	return number, hash, nil
+}
+
+// resolveRange resolves the From and To references of the options into block numbers.
+// Block numbers must refer to existing blocks up to the finalized head when To is BlockFinalized, the best block otherwise,
+// so a range past the head fails here instead of once every block before it has been processed.
+func resolveRange(api *gsrpc.SubstrateAPI, opts Options) (from, to uint64, err error) {
+	toRef := opts.To
+	if toRef == "" {
+		toRef = BlockFinalized
+	}
+
+	headRef := BlockLatest
+	if isBlockRef(toRef, BlockFinalized) {
+		headRef = BlockFinalized
+	}
+	head, err := resolveBlock(api, headRef)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if opts.From != "" {
+		from, err = resolveRangeBlock(api, opts.From, headRef, head)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	to, err = resolveRangeBlock(api, toRef, headRef, head)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if isBlockRef(toRef, BlockLatest) || isBlockRef(toRef, BlockFinalized) {
+		if to < opts.Confirmations {
+			return 0, 0, fmt.Errorf("head %d has fewer than %d confirmations", to, opts.Confirmations)
+		}
+		to -= opts.Confirmations
+	}
+
+	if from > to {
+		return 0, 0, fmt.Errorf("start block %d is after end block %d", from, to)
+	}
+
+	return from, to, nil
+}
+
+// resolveRangeBlock resolves a bound of the range, checking a block number against the given head and the node
+func resolveRangeBlock(api *gsrpc.SubstrateAPI, ref, headRef string, head uint64) (uint64, error) {
+	number, err := resolveBlock(api, ref)
+	if err != nil {
+		return 0, err
+	}
+	if !isBlockNumber(ref) {
+		return number, nil
+	}
+
+	if number > head {
+		return 0, fmt.Errorf("block %d is after the %s block %d", number, headRef, head)
+	}
+	if _, err := api.RPC.Chain.GetBlockHash(number); err != nil {
+		return 0, fmt.Errorf("block %d not found: %s", number, err.Error())
+	}
+
+	return number, nil
+}
+
+// isBlockRef reports whether ref is the given keyword
+func isBlockRef(ref, keyword string) bool {
+	return strings.ToLower(strings.TrimSpace(ref)) == keyword
+}
+
+// isBlockNumber reports whether ref is a block number rather than a hash or keyword
+func isBlockNumber(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	return ref != "" && !strings.HasPrefix(ref, "0x") && !isBlockRef(ref, BlockLatest) && !isBlockRef(ref, BlockFinalized)
+}
+
+func blockNumber(api *gsrpc.SubstrateAPI, hash types.Hash) (uint64, error) {
+	header, err := api.RPC.Chain.GetHeader(hash)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(header.Number), nil
+}
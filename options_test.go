@@ -0,0 +1,23 @@
+package account_scraper
+
+import "testing"
+
+func TestIsBlockNumber(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want bool
+	}{
+		{"0", true},
+		{" 1234 ", true},
+		{"latest", false},
+		{"Finalized", false},
+		{"0x9a6c1d2e5f3b4a7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := isBlockNumber(test.ref); got != test.want {
+			t.Errorf("isBlockNumber(%q) = %t, want %t", test.ref, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package account_scraper
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/scale"
+)
+
+// Progress tells how far a run got through its block range
+type Progress struct {
+	// From and To are the first and last block of the range processed in this run
+	From uint64
+	To   uint64
+	// Block is the last block processed
+	Block uint64
+	// Events is the number of events processed in this run
+	Events uint64
+	// Accounts is the number of accounts found so far, including appended, resumed and account set ones
+	Accounts int
+	// Elapsed is the time since the run started processing blocks
+	Elapsed time.Duration
+}
+
+// Blocks returns the number of blocks processed in this run
+func (p Progress) Blocks() uint64 {
+	if p.Block < p.From {
+		return 0
+	}
+
+	return p.Block - p.From + 1
+}
+
+// Percent returns the share of the range processed, from 0 to 100
+func (p Progress) Percent() float64 {
+	if p.To < p.From {
+		return 100
+	}
+
+	return float64(p.Blocks()) * 100 / float64(p.To-p.From+1)
+}
+
+// BlocksPerSecond returns the average throughput of this run in blocks
+func (p Progress) BlocksPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(p.Blocks()) / p.Elapsed.Seconds()
+}
+
+// EventsPerSecond returns the average throughput of this run in events
+func (p Progress) EventsPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(p.Events) / p.Elapsed.Seconds()
+}
+
+// ETA estimates the time left until To is reached at the average throughput, zero when unknown or done
+func (p Progress) ETA() time.Duration {
+	rate := p.BlocksPerSecond()
+	if rate == 0 || p.Block >= p.To {
+		return 0
+	}
+
+	return time.Duration(float64(p.To-p.Block) / rate * float64(time.Second))
+}
+
+// eventCount returns the number of events in the raw System.Events of a block, read from the length prefix
+func eventCount(raw []byte) uint64 {
+	n, err := scale.NewDecoder(bytes.NewReader(raw)).DecodeUintCompact()
+	if err != nil {
+		return 0
+	}
+
+	return n.Uint64()
+}
@@ -0,0 +1,71 @@
+package account_scraper
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/scale"
+)
+
+func TestProgress(t *testing.T) {
+	tests := []struct {
+		name      string
+		p         Progress
+		blocks    uint64
+		percent   float64
+		perSecond float64
+		eta       time.Duration
+	}{
+		{"not started", Progress{From: 100, To: 199, Block: 99, Elapsed: time.Second}, 0, 0, 0, 0},
+		{"quarter", Progress{From: 100, To: 199, Block: 124, Elapsed: 5 * time.Second}, 25, 25, 5, 15 * time.Second},
+		{"done", Progress{From: 100, To: 199, Block: 199, Elapsed: 10 * time.Second}, 100, 100, 10, 0},
+		{"no time elapsed", Progress{From: 100, To: 199, Block: 149}, 50, 50, 0, 0},
+		{"single block", Progress{From: 7, To: 7, Block: 7, Elapsed: time.Second}, 1, 100, 1, 0},
+		{"empty range", Progress{From: 8, To: 7, Block: 7}, 0, 100, 0, 0},
+		{"following past the end", Progress{From: 100, To: 199, Block: 249, Elapsed: 10 * time.Second}, 150, 150, 15, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.p.Blocks(); got != test.blocks {
+				t.Errorf("Blocks() = %d, want %d", got, test.blocks)
+			}
+			if got := test.p.Percent(); got != test.percent {
+				t.Errorf("Percent() = %f, want %f", got, test.percent)
+			}
+			if got := test.p.BlocksPerSecond(); got != test.perSecond {
+				t.Errorf("BlocksPerSecond() = %f, want %f", got, test.perSecond)
+			}
+			if got := test.p.ETA(); got != test.eta {
+				t.Errorf("ETA() = %s, want %s", got, test.eta)
+			}
+		})
+	}
+
+	p := Progress{Events: 300, Elapsed: 2 * time.Second}
+	if got := p.EventsPerSecond(); got != 150 {
+		t.Errorf("EventsPerSecond() = %f, want 150", got)
+	}
+}
+
+func TestEventCount(t *testing.T) {
+	for _, n := range []int64{0, 1, 63, 64, 20000} {
+		var buf bytes.Buffer
+		err := scale.NewEncoder(&buf).EncodeUintCompact(*big.NewInt(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the events themselves follow the length prefix
+		buf.Write([]byte{1, 2, 3})
+
+		if got := eventCount(buf.Bytes()); got != uint64(n) {
+			t.Errorf("eventCount = %d, want %d", got, n)
+		}
+	}
+
+	if got := eventCount(nil); got != 0 {
+		t.Errorf("eventCount of no data = %d, want 0", got)
+	}
+}
@@ -0,0 +1,112 @@
+package account_scraper
+
+import (
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// SourceFile is the source of accounts loaded from an account list.
+// Accounts from account sets have the kind of the set as source (e.g. genesis or test), the others the name of their event.
+const SourceFile = "file"
+
+// Provenance records when and why an account was first added to a set
+type Provenance struct {
+	// Source is the event the account was found in, the kind of the account set it was added from or SourceFile
+	Source string `json:"source"`
+	// Set is the account set the account was added from
+	Set         string `json:"set,omitempty"`
+	BlockNumber uint64 `json:"block_number,omitempty"`
+	BlockHash   string `json:"block_hash,omitempty"`
+	// ExtrinsicIndex is the index in the block of the extrinsic which emitted the event, nil for events
+	// emitted during initialization or finalization of the block
+	ExtrinsicIndex *uint32 `json:"extrinsic_index,omitempty"`
+	// Amount is the decimal balance the event moved to the account, empty for events without one
+	Amount string `json:"amount,omitempty"`
+	// File is the account list the account was loaded from
+	File string `json:"file,omitempty"`
+}
+
+// AccountSet holds accounts along with the provenance of their first sighting
+type AccountSet map[types.AccountID]Provenance
+
+// add inserts the account unless the set already has it and reports whether it did
+func (s AccountSet) add(acc types.AccountID, p Provenance) bool {
+	if _, ok := s[acc]; ok {
+		return false
+	}
+	s[acc] = p
+
+	return true
+}
+
+// clone returns a copy of the set
+func (s AccountSet) clone() AccountSet {
+	c := make(AccountSet, len(s))
+	for acc, p := range s {
+		c[acc] = p
+	}
+
+	return c
+}
+
+// AccountRecord is an account with its provenance, the entry of the provenance format
+type AccountRecord struct {
+	Account string `json:"account"`
+	SS58    string `json:"ss58,omitempty"`
+	Provenance
+}
+
+// Sorted returns the accounts of the set in canonical order, ascending by bytes
+func (s AccountSet) Sorted() []types.AccountID {
+	return sortedAccounts(s)
+}
+
+// records returns the accounts of the set as records in canonical order
+func (s AccountSet) records(prefix uint16) ([]AccountRecord, error) {
+	accounts := sortedAccounts(s)
+	records := make([]AccountRecord, 0, len(accounts))
+	for _, acc := range accounts {
+		address, err := SS58Encode(acc, prefix)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, AccountRecord{
+			Account:    hexutil.Encode(acc[:]),
+			SS58:       address,
+			Provenance: s[acc],
+		})
+	}
+
+	return records, nil
+}
+
+// addRecords adds the accounts of the records to the set
+func (s AccountSet) addRecords(records []AccountRecord) error {
+	for _, r := range records {
+		acc, err := ParseAccount(r.Account)
+		if err != nil {
+			return err
+		}
+		s.add(acc, r.Provenance)
+	}
+
+	return nil
+}
+
+// eventProvenance returns the provenance of an account found in an event of the given block
+func eventProvenance(source string, number uint64, hash types.Hash, acc EventAccount) Provenance {
+	p := Provenance{
+		Source:      source,
+		BlockNumber: number,
+		BlockHash:   hash.Hex(),
+	}
+	if acc.Phase.IsApplyExtrinsic {
+		index := acc.Phase.AsApplyExtrinsic
+		p.ExtrinsicIndex = &index
+	}
+	if acc.Amount != nil {
+		p.Amount = acc.Amount.String()
+	}
+
+	return p
+}
@@ -0,0 +1,65 @@
+package account_scraper
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+func TestProvenanceRoundTrip(t *testing.T) {
+	index := uint32(3)
+	var other types.AccountID
+	other[0] = 1
+
+	accountSet := AccountSet{
+		alice: {Source: "Balances.Endowed", BlockNumber: 12, BlockHash: "0x01", ExtrinsicIndex: &index, Amount: "1000"},
+		other: {Source: "genesis", Set: "genesis-amber"},
+	}
+
+	data, err := encodeAccounts(accountSet, FormatProvenance, CentrifugePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := decodeAccounts(data, FormatProvenance, "accounts.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, accountSet) {
+		t.Errorf("decoded %+v, want %+v", decoded, accountSet)
+	}
+}
+
+func TestEventProvenance(t *testing.T) {
+	hash := types.NewHash([]byte{1})
+
+	p := eventProvenance("Balances.Transfer", 5, hash, EventAccount{
+		Account: alice,
+		Phase:   types.Phase{IsApplyExtrinsic: true, AsApplyExtrinsic: 2},
+		Amount:  big.NewInt(42),
+	})
+	if p.Source != "Balances.Transfer" || p.BlockNumber != 5 || p.BlockHash != hash.Hex() ||
+		p.ExtrinsicIndex == nil || *p.ExtrinsicIndex != 2 || p.Amount != "42" {
+		t.Errorf("got provenance %+v", p)
+	}
+
+	p = eventProvenance("System.NewAccount", 5, hash, EventAccount{Account: alice, Phase: types.Phase{IsFinalization: true}})
+	if p.ExtrinsicIndex != nil || p.Amount != "" {
+		t.Errorf("got extrinsic index and amount for an event without them: %+v", p)
+	}
+}
+
+func TestAccountSetAdd(t *testing.T) {
+	accountSet := make(AccountSet)
+	if !accountSet.add(alice, Provenance{Source: "first"}) {
+		t.Error("adding a new account reported it as known")
+	}
+	if accountSet.add(alice, Provenance{Source: "second"}) {
+		t.Error("adding a known account reported it as new")
+	}
+	if accountSet[alice].Source != "first" {
+		t.Errorf("provenance replaced by %+v, want the first sighting", accountSet[alice])
+	}
+}
@@ -0,0 +1,136 @@
+package account_scraper
+
+import (
+	"context"
+	"fmt"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+// reorgDepth is the number of processed ranges whose last block is remembered to find the common ancestor after a reorg
+const reorgDepth = 1024
+
+// processedBlock is the last block of a processed range
+type processedBlock struct {
+	number uint64
+	hash   types.Hash
+}
+
+// processedBlocks remembers the last blocks of recently processed ranges, oldest first
+type processedBlocks struct {
+	blocks []processedBlock
+}
+
+// push records the last block of a processed range
+func (p *processedBlocks) push(number uint64, hash types.Hash) {
+	p.blocks = append(p.blocks, processedBlock{number: number, hash: hash})
+	if len(p.blocks) > reorgDepth {
+		p.blocks = p.blocks[len(p.blocks)-reorgDepth:]
+	}
+}
+
+// last returns the most recently processed block
+func (p *processedBlocks) last() (processedBlock, bool) {
+	if len(p.blocks) == 0 {
+		return processedBlock{}, false
+	}
+
+	return p.blocks[len(p.blocks)-1], true
+}
+
+// rewind drops the blocks which are no longer canonical and returns the most recent one which still is.
+// canonical returns the hash of the canonical block with the given number.
+func (p *processedBlocks) rewind(canonical func(number uint64) (types.Hash, error)) (processedBlock, error) {
+	for len(p.blocks) > 0 {
+		b := p.blocks[len(p.blocks)-1]
+		hash, err := canonical(b.number)
+		if err != nil {
+			return processedBlock{}, err
+		}
+		if hash == b.hash {
+			return b, nil
+		}
+		p.blocks = p.blocks[:len(p.blocks)-1]
+	}
+
+	return processedBlock{}, fmt.Errorf("reorg is deeper than the last %d processed ranges", reorgDepth)
+}
+
+// canonicalHash returns the hash of the canonical block with the given number
+func canonicalHash(ctx context.Context, conn *connection, number uint64) (types.Hash, error) {
+	var hash types.Hash
+	err := conn.call(ctx, func(api *gsrpc.SubstrateAPI) (err error) {
+		hash, err = api.RPC.Chain.GetBlockHash(number)
+		return err
+	})
+
+	return hash, err
+}
+
+// parentHash returns the parent hash of the canonical block with the given number
+func parentHash(ctx context.Context, conn *connection, number uint64) (types.Hash, error) {
+	var parent types.Hash
+	err := conn.call(ctx, func(api *gsrpc.SubstrateAPI) error {
+		hash, err := api.RPC.Chain.GetBlockHash(number)
+		if err != nil {
+			return err
+		}
+
+		header, err := api.RPC.Chain.GetHeader(hash)
+		if err != nil {
+			return err
+		}
+		parent = header.ParentHash
+		return nil
+	})
+
+	return parent, err
+}
+
+// rollback removes everything found in blocks after ancestor and returns the removed accounts.
+// Kills undone by account events after ancestor are restored.
+// Accounts loaded from files, account sets or state have no block hash and are kept.
+func (f *findings) rollback(ancestor uint64) []types.AccountID {
+	var removed []types.AccountID
+	for _, acc := range sortedAccounts(f.accounts) {
+		p := f.accounts[acc]
+		if p.BlockHash != "" && p.Source != SourceState && p.BlockNumber > ancestor {
+			delete(f.accounts, acc)
+			removed = append(removed, acc)
+		}
+	}
+
+	// the last change left tells whether the account is killed, kills undone in orphaned blocks hold again
+	for acc, changes := range f.changes {
+		kept := len(changes)
+		for kept > 0 && changes[kept-1].number > ancestor {
+			kept--
+		}
+		if kept == len(changes) {
+			continue
+		}
+
+		if kept == 0 {
+			delete(f.changes, acc)
+			delete(f.killed, acc)
+			continue
+		}
+		f.changes[acc] = changes[:kept]
+		if last := changes[kept-1]; last.killed {
+			f.killed[acc] = last.number
+		} else {
+			delete(f.killed, acc)
+		}
+	}
+
+	failures := f.failures[:0]
+	for _, failure := range f.failures {
+		if failure.BlockNumber <= ancestor {
+			failures = append(failures, failure)
+		}
+	}
+	f.failures = failures
+
+	return removed
+}
@@ -0,0 +1,130 @@
+package account_scraper
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+func TestProcessedBlocksRewind(t *testing.T) {
+	orphaned := types.NewHash([]byte{0xff})
+	p := &processedBlocks{}
+	for n := uint64(1); n <= 5; n++ {
+		p.push(n*10, fakeHash(n*10))
+	}
+
+	// blocks after 30 were replaced
+	canonical := func(number uint64) (types.Hash, error) {
+		if number > 30 {
+			return orphaned, nil
+		}
+		return fakeHash(number), nil
+	}
+
+	ancestor, err := p.rewind(canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ancestor.number != 30 || ancestor.hash != fakeHash(30) {
+		t.Errorf("rewound to block %d, want 30", ancestor.number)
+	}
+	if last, _ := p.last(); last != ancestor {
+		t.Errorf("last block after rewinding is %d, want the ancestor", last.number)
+	}
+
+	_, err = p.rewind(func(number uint64) (types.Hash, error) { return orphaned, nil })
+	if err == nil {
+		t.Error("rewinding past every processed block succeeded")
+	}
+	if _, ok := p.last(); ok {
+		t.Error("processed blocks left after rewinding past all of them")
+	}
+}
+
+func TestProcessedBlocksDepth(t *testing.T) {
+	p := &processedBlocks{}
+	for n := uint64(1); n <= reorgDepth+10; n++ {
+		p.push(n, fakeHash(n))
+	}
+
+	if len(p.blocks) != reorgDepth || p.blocks[0].number != 11 {
+		t.Errorf("kept %d blocks from %d, want %d from 11", len(p.blocks), p.blocks[0].number, reorgDepth)
+	}
+	if last, _ := p.last(); last.number != reorgDepth+10 {
+		t.Errorf("last block %d, want %d", last.number, reorgDepth+10)
+	}
+}
+
+func TestFindingsRollback(t *testing.T) {
+	loaded, early, late := account(1), account(2), account(3)
+
+	found := newFindings()
+	found.accounts.add(loaded, Provenance{Source: SourceFile, File: "accounts.scale"})
+	found.accounts.add(early, Provenance{Source: "Balances.Endowed", BlockNumber: 5, BlockHash: "0x05"})
+	found.revive(early, 5)
+	found.accounts.add(late, Provenance{Source: "Balances.Endowed", BlockNumber: 15, BlockHash: "0x15"})
+	found.revive(late, 15)
+	found.failures = []DecodeFailure{{BlockNumber: 8}, {BlockNumber: 12}}
+
+	// early is reaped before the ancestor and revived after it, late is reaped after it
+	found.kill(early, 7)
+	found.revive(early, 12)
+	found.kill(late, 16)
+
+	removed := found.rollback(10)
+
+	if len(removed) != 1 || removed[0] != late {
+		t.Errorf("rolled back %x, want only the account found after the ancestor", removed)
+	}
+	if _, ok := found.accounts[loaded]; !ok {
+		t.Error("account loaded from a file was rolled back")
+	}
+	if found.killed[early] != 7 {
+		t.Errorf("early killed at %d, want the kill at 7 undone in an orphaned block restored", found.killed[early])
+	}
+	if _, ok := found.killed[late]; ok {
+		t.Error("kill in an orphaned block kept")
+	}
+	if len(found.failures) != 1 || found.failures[0].BlockNumber != 8 {
+		t.Errorf("kept failures %+v, want the one of block 8", found.failures)
+	}
+
+	// the canonical blocks revive early again and processing continues from there
+	canonical := newFindings()
+	canonical.accounts.add(early, Provenance{Source: "Balances.Transfer", BlockNumber: 11, BlockHash: "0x11"})
+	canonical.revive(early, 11)
+	found.merge(canonical)
+	if _, ok := found.killed[early]; ok {
+		t.Error("early revived in a canonical block is still killed")
+	}
+}
+
+func TestFindingsRollbackRepeatedKills(t *testing.T) {
+	a := account(1)
+	found := newFindings()
+	found.revive(a, 1)
+	found.kill(a, 2)
+	found.revive(a, 3)
+	found.kill(a, 4)
+	found.revive(a, 5)
+
+	tests := []struct {
+		ancestor uint64
+		killed   bool
+		at       uint64
+	}{
+		{5, false, 0},
+		{4, true, 4},
+		{3, false, 0},
+		{2, true, 2},
+		{1, false, 0},
+	}
+
+	for _, test := range tests {
+		found.rollback(test.ancestor)
+		at, killed := found.killed[a]
+		if killed != test.killed || at != test.at {
+			t.Errorf("after rolling back to %d killed %t at %d, want %t at %d", test.ancestor, killed, at, test.killed, test.at)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package account_scraper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/pkg/errors"
+)
+
+// reportFile is the name of the file listing the blocks whose events could not be decoded,
+// written next to the account list unless configured otherwise
+const reportFile = "decode_failures.json"
+
+// ErrDecodeFailures is returned in strict mode when the events of blocks could not be decoded
+var ErrDecodeFailures = errors.New("events could not be decoded")
+
+// DecodeFailure describes a block whose events could not be decoded, so accounts it created may be missing
+type DecodeFailure struct {
+	BlockNumber uint64 `json:"block_number"`
+	BlockHash   string `json:"block_hash"`
+	Error       string `json:"error"`
+	// RawEvents is the hex encoded System.Events storage of the block
+	RawEvents string `json:"raw_events"`
+}
+
+// findings collects what processing blocks turned up
+type findings struct {
+	accounts AccountSet
+	failures []DecodeFailure
+	// killed holds the accounts reaped after their last appearance in an account event, with the block of the kill
+	killed map[types.AccountID]uint64
+	// changes holds the kills of accounts and their appearances in account events in block order, appearances only
+	// when the account is not known to be live yet. Merging replays them, rolling back drops the orphaned ones.
+	changes map[types.AccountID][]lifeChange
+	// events is the number of events processed
+	events uint64
+}
+
+// lifeChange is a kill of an account or its appearance in an account event
+type lifeChange struct {
+	number uint64
+	killed bool
+}
+
+func newFindings() *findings {
+	return &findings{
+		accounts: make(AccountSet),
+		killed:   make(map[types.AccountID]uint64),
+		changes:  make(map[types.AccountID][]lifeChange),
+	}
+}
+
+// kill records the account was reaped in the given block
+func (f *findings) kill(acc types.AccountID, number uint64) {
+	f.killed[acc] = number
+	f.changes[acc] = append(f.changes[acc], lifeChange{number: number, killed: true})
+}
+
+// revive records the account appeared in an account event of the given block after any kill recorded so far
+func (f *findings) revive(acc types.AccountID, number uint64) {
+	delete(f.killed, acc)
+	changes := f.changes[acc]
+	if len(changes) == 0 || changes[len(changes)-1].killed {
+		f.changes[acc] = append(changes, lifeChange{number: number})
+	}
+}
+
+// merge adds the findings of other to f and returns the accounts f did not contain yet.
+// Accounts f already holds keep their provenance, so merging in block order keeps the first sighting.
+func (f *findings) merge(other *findings) []types.AccountID {
+	var added []types.AccountID
+	for _, acc := range sortedAccounts(other.accounts) {
+		if f.accounts.add(acc, other.accounts[acc]) {
+			added = append(added, acc)
+		}
+	}
+	f.failures = append(f.failures, other.failures...)
+	f.events += other.events
+
+	// other covers later blocks, replaying its changes keeps only kills after any revival
+	for acc, changes := range other.changes {
+		for _, c := range changes {
+			if c.killed {
+				f.kill(acc, c.number)
+			} else {
+				f.revive(acc, c.number)
+			}
+		}
+	}
+
+	return added
+}
+
+// saveReport writes the decode failures as JSON, an empty list meaning every block was decoded
+func saveReport(path string, failures []DecodeFailure) error {
+	if failures == nil {
+		failures = []DecodeFailure{}
+	}
+
+	data, err := json.MarshalIndent(failures, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,91 @@
+package account_scraper
+
+import (
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+// account returns a test account whose first byte is b
+func account(b byte) types.AccountID {
+	var acc types.AccountID
+	acc[0] = b
+	return acc
+}
+
+func TestFindingsMergeKillRevive(t *testing.T) {
+	a, b, c := account(1), account(2), account(3)
+
+	found := newFindings()
+	first := newFindings()
+	first.accounts.add(a, Provenance{Source: "Balances.Endowed", BlockNumber: 1})
+	first.revive(a, 1)
+	first.accounts.add(b, Provenance{Source: "Balances.Endowed", BlockNumber: 2})
+	first.revive(b, 2)
+	// b is reaped after its endowment, c is reaped without being found in this range
+	first.kill(b, 3)
+	first.kill(c, 4)
+	found.merge(first)
+
+	if _, ok := found.killed[b]; !ok {
+		t.Error("b reaped after its last event is not killed")
+	}
+	if _, ok := found.killed[a]; ok {
+		t.Error("a is killed without being reaped")
+	}
+
+	second := newFindings()
+	// a later range reaps a, then b is endowed again and c is reaped again
+	second.kill(a, 10)
+	second.accounts.add(b, Provenance{Source: "Balances.Transfer", BlockNumber: 11})
+	second.revive(b, 11)
+	second.kill(c, 12)
+	added := found.merge(second)
+
+	if len(added) != 0 {
+		t.Errorf("merge added %x, all accounts were known", added)
+	}
+	if found.killed[a] != 10 {
+		t.Errorf("a killed at %d, want 10", found.killed[a])
+	}
+	if _, ok := found.killed[b]; ok {
+		t.Error("b revived in a later range is still killed")
+	}
+	if found.killed[c] != 12 {
+		t.Errorf("c killed at %d, want the later kill at 12", found.killed[c])
+	}
+	if found.accounts[b].BlockNumber != 2 {
+		t.Errorf("b has provenance %+v, want its first sighting", found.accounts[b])
+	}
+}
+
+func TestFindingsReviveThenKillInRange(t *testing.T) {
+	a := account(1)
+
+	found := newFindings()
+	found.kill(a, 1)
+
+	// a range which endows a and reaps it again leaves it killed at its own kill
+	later := newFindings()
+	later.accounts.add(a, Provenance{Source: "Balances.Endowed", BlockNumber: 5})
+	later.revive(a, 5)
+	later.kill(a, 6)
+	found.merge(later)
+
+	if found.killed[a] != 6 {
+		t.Errorf("a killed at %d, want 6", found.killed[a])
+	}
+}
+
+func TestFindingsMergeCounts(t *testing.T) {
+	found := newFindings()
+	other := newFindings()
+	other.events = 7
+	other.failures = []DecodeFailure{{BlockNumber: 3}}
+	other.accounts.add(account(1), Provenance{Source: "System.NewAccount"})
+
+	added := found.merge(other)
+	if len(added) != 1 || found.events != 7 || len(found.failures) != 1 {
+		t.Errorf("merged %d accounts, %d events and %d failures, want 1, 7 and 1", len(added), found.events, len(found.failures))
+	}
+}
@@ -0,0 +1,228 @@
+package account_scraper
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultMaxAttempts is the number of times a failing range is tried before giving up
+	DefaultMaxAttempts = 5
+	// DefaultBackoff is the wait before the first retry, doubled after every further failure
+	DefaultBackoff = time.Second
+	maxBackoff     = time.Minute
+)
+
+// errorClass tells whether an operation that failed with an error is worth retrying
+type errorClass int
+
+const (
+	errPermanent errorClass = iota
+	errTransient
+	// errTooLarge is returned for requests covering too much data, they need to be split rather than retried
+	errTooLarge
+)
+
+func (c errorClass) String() string {
+	switch c {
+	case errTransient:
+		return "transient"
+	case errTooLarge:
+		return "too large"
+	}
+	return "permanent"
+}
+
+// rpcError matches the errors returned by the node in a JSON-RPC response
+type rpcError interface {
+	Error() string
+	ErrorCode() int
+}
+
+// transientMessages are fragments of errors caused by the connection rather than the request
+var transientMessages = []string{
+	"client is closed",
+	"connection lost",
+	"client reconnected",
+	"connection reset",
+	"connection refused",
+	"broken pipe",
+	"websocket: close",
+	"EOF",
+	"timed out",
+	"timeout",
+}
+
+// classify tells transient errors (connection drops, timeouts) and oversized requests apart from permanent ones
+func classify(err error) errorClass {
+	if err == nil {
+		return errPermanent
+	}
+
+	if isTooLarge(err) {
+		return errTooLarge
+	}
+
+	cause := errors.Cause(err)
+	if cause == io.EOF || cause == io.ErrUnexpectedEOF {
+		return errTransient
+	}
+
+	if _, ok := cause.(net.Error); ok {
+		return errTransient
+	}
+
+	// the node answered, so the connection is fine. Malformed requests (-32700 to -32600) and server errors
+	// (-32000 and below) such as unknown blocks or state discarded by a pruned node yield the same error again.
+	if _, ok := cause.(rpcError); ok {
+		return errPermanent
+	}
+
+	msg := err.Error()
+	for _, m := range transientMessages {
+		if strings.Contains(msg, m) {
+			return errTransient
+		}
+	}
+
+	return errPermanent
+}
+
+// retry runs op until it succeeds, fails with a non transient error, maxAttempts is reached or ctx is done.
+// The wait between attempts starts at backoff and doubles every time, capped at maxBackoff.
+func retry(ctx context.Context, maxAttempts int, backoff time.Duration, op func() error) error {
+	return retryOn(ctx, errTransient, maxAttempts, backoff, op)
+}
+
+// retryOn runs op like retry, but retries the errors of the given class instead of the transient ones
+func retryOn(ctx context.Context, class errorClass, maxAttempts int, backoff time.Duration, op func() error) error {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		err = op()
+		if err == nil || classify(err) != class {
+			return err
+		}
+
+		if attempt < maxAttempts {
+			logger.Warn("Attempt failed, retrying", "attempt", attempt, "max_attempts", maxAttempts, "class", classify(err),
+				"err", err, "backoff", backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+
+	return errors.Wrapf(err, "giving up after %d attempts", maxAttempts)
+}
+
+// connection holds the API to the node and replaces it when the websocket drops
+type connection struct {
+	url         string
+	maxAttempts int
+	backoff     time.Duration
+
+	mu  sync.RWMutex
+	api *gsrpc.SubstrateAPI
+}
+
+// newConnection connects to the node, zero maxAttempts and backoff default to DefaultMaxAttempts and DefaultBackoff
+func newConnection(ctx context.Context, url string, maxAttempts int, backoff time.Duration) (*connection, error) {
+	if maxAttempts == 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if backoff == 0 {
+		backoff = DefaultBackoff
+	}
+
+	c := &connection{url: url, maxAttempts: maxAttempts, backoff: backoff}
+	err := retry(ctx, maxAttempts, backoff, func() error {
+		api, err := gsrpc.NewSubstrateAPI(url)
+		if err != nil {
+			return err
+		}
+		c.api = api
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// get returns the current API
+func (c *connection) get() *gsrpc.SubstrateAPI {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.api
+}
+
+// reconnect replaces stale with a new API, unless another caller already did
+func (c *connection) reconnect(stale *gsrpc.SubstrateAPI) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.api != stale {
+		return nil
+	}
+
+	logger.Warn("Reconnecting", "url", c.url)
+	api, err := gsrpc.NewSubstrateAPI(c.url)
+	if err != nil {
+		return err
+	}
+
+	if cl, ok := stale.Client.(interface{ Close() }); ok {
+		cl.Close()
+	}
+	c.api = api
+	return nil
+}
+
+// call runs op against the current API with retries, reconnecting after transient errors.
+// Oversized responses make the client drop the websocket, so those reconnect as well.
+// The client does not take a context, so when ctx is done call returns right away and op is left to finish unobserved.
+func (c *connection) call(ctx context.Context, op func(api *gsrpc.SubstrateAPI) error) error {
+	return retry(ctx, c.maxAttempts, c.backoff, func() error {
+		api := c.get()
+		result := make(chan error, 1)
+		go func() {
+			result <- op(api)
+		}()
+
+		var err error
+		select {
+		case err = <-result:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err != nil && classify(err) != errPermanent {
+			rerr := c.reconnect(api)
+			if rerr != nil {
+				// surface the failed reconnect as a transient error so the next attempt tries again
+				return errors.Wrap(err, rerr.Error())
+			}
+		}
+		return err
+	})
+}
@@ -0,0 +1,107 @@
+package account_scraper
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// codeError is a JSON-RPC error response of the node
+type codeError struct {
+	code int
+	msg  string
+}
+
+func (e codeError) Error() string  { return e.msg }
+func (e codeError) ErrorCode() int { return e.code }
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want errorClass
+	}{
+		{"nil", nil, errPermanent},
+		{"eof", io.EOF, errTransient},
+		{"wrapped eof", errors.Wrap(io.ErrUnexpectedEOF, "reading"), errTransient},
+		{"net error", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("no route to host")}, errTransient},
+		{"connection lost", errors.New("client: connection lost"), errTransient},
+		{"websocket closed", errors.New("websocket: close 1006 (abnormal closure)"), errTransient},
+		{"i/o timeout", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("i/o timeout")}, errTransient},
+		{"timed out", errors.New("request timed out"), errTransient},
+		{"read limit", errors.New("websocket: read limit exceeded"), errTooLarge},
+		{"too big response", codeError{-32000, "Response is too big"}, errTooLarge},
+		{"parse error", codeError{-32700, "parse error"}, errPermanent},
+		{"invalid params", codeError{-32602, "invalid params"}, errPermanent},
+		{"unknown block", codeError{-32000, "Client error: UnknownBlock: State already discarded"}, errPermanent},
+		{"server error", codeError{-32099, "server error"}, errPermanent},
+		{"wrapped server error", errors.Wrap(codeError{-32000, "unknown block"}, "range 1 - 2"), errPermanent},
+		{"unknown", errors.New("invalid storage key"), errPermanent},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := classify(test.err); got != test.want {
+				t.Errorf("classify(%v) = %s, want %s", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRetryAttempts(t *testing.T) {
+	transient := errors.New("connection reset by peer")
+	permanent := errors.New("invalid storage key")
+
+	tests := []struct {
+		name        string
+		maxAttempts int
+		// errs are returned by the attempts in order, attempts after them succeed
+		errs         []error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"success", 3, nil, 1, false},
+		{"transient then success", 3, []error{transient, transient}, 3, false},
+		{"transient until giving up", 3, []error{transient, transient, transient, transient}, 3, true},
+		{"permanent", 3, []error{permanent, transient}, 1, true},
+		{"transient then permanent", 3, []error{transient, permanent}, 2, true},
+		{"at least one attempt", 0, []error{transient}, 1, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			err := retry(context.Background(), test.maxAttempts, 0, func() error {
+				attempts++
+				if attempts <= len(test.errs) {
+					return test.errs[attempts-1]
+				}
+				return nil
+			})
+
+			if attempts != test.wantAttempts {
+				t.Errorf("got %d attempts, want %d", attempts, test.wantAttempts)
+			}
+			if (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want error %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := retry(ctx, 3, 0, func() error {
+		attempts++
+		return nil
+	})
+	if err != context.Canceled || attempts != 0 {
+		t.Errorf("got %d attempts and error %v, want none and %v", attempts, err, context.Canceled)
+	}
+}
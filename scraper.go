@@ -0,0 +1,68 @@
+package account_scraper
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInterrupted is returned by Run when it was stopped or its context was done before reaching the end of the range
+var ErrInterrupted = errors.New("scraping was interrupted")
+
+// Scraper collects the accounts of a chain as configured by its Options
+type Scraper struct {
+	opts     Options
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewScraper returns a Scraper for the options
+func NewScraper(opts Options) *Scraper {
+	return &Scraper{opts: opts, stop: make(chan struct{})}
+}
+
+// Stop makes Run finish the block ranges in flight, save the accounts found so far and return ErrInterrupted
+func (sc *Scraper) Stop() {
+	sc.stopOnce.Do(func() {
+		close(sc.stop)
+	})
+}
+
+// Result is the outcome of a Scraper run
+type Result struct {
+	// From and To are the first and last block of the range
+	From uint64
+	To   uint64
+	// LastBlock is the last block processed, including those of a resumed run
+	LastBlock uint64
+	// Interrupted tells the run was stopped or cancelled before LastBlock reached To, the checkpoint allows resuming it.
+	// The account list was saved without the verifications of OnlyLive and MinBalance.
+	Interrupted bool
+	// Accounts are the accounts written to Output with their provenance, including appended, resumed and account set ones.
+	// Accounts dropped by OnlyLive and MinBalance are left out.
+	Accounts AccountSet
+	// Output is the file the account list was written to and Checksum the SHA-256 of its content
+	Output   string
+	Checksum string
+	// Failures are the blocks whose events failed to decode, listed in Report
+	Failures []DecodeFailure
+	Report   string
+}
+
+// Run scrapes the configured block range and writes the account list. Accounts are passed to Options.OnAccount
+// as they are found. In Follow mode Run only returns once stopped, ctx is done or following fails.
+// When ctx is done, pending RPC calls are abandoned. Both that and Stop save the accounts found so far
+// and return ErrInterrupted. On other errors the result holds what was found until then.
+func (sc *Scraper) Run(ctx context.Context) (*Result, error) {
+	result := &Result{}
+	err := sc.process(ctx, result)
+
+	return result, err
+}
+
+// Process runs a Scraper with the options until done
+func Process(opts Options) error {
+	_, err := NewScraper(opts).Run(context.Background())
+	return err
+}
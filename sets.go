@@ -0,0 +1,132 @@
+package account_scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+//go:generate go run gen_sets.go
+
+// DefaultAccountSetsPath is the file with the genesis and test account lists of the Centrifuge networks.
+// Its content is built in, so the default sets are used when no such file is found relative to the working directory.
+const DefaultAccountSetsPath = "config/accounts.yaml"
+
+// setsFile is the content of an account sets file: lists of accounts of a kind (genesis, test, ...) per network
+type setsFile struct {
+	Networks map[string]map[string][]string `json:"networks" yaml:"networks" toml:"networks"`
+}
+
+// namedSet is a named list of accounts loaded from a sets file
+type namedSet struct {
+	name     string
+	kind     string
+	network  string
+	accounts []string
+}
+
+// matches tells whether the set is selected by the given selector: its name, its kind or its network
+func (s namedSet) matches(selector string) bool {
+	return selector == s.name || selector == s.kind || selector == s.network
+}
+
+// loadAccountSets reads the sets of the given files, which are parsed as YAML, JSON or TOML based on their extension
+func loadAccountSets(paths []string) ([]namedSet, error) {
+	var sets []namedSet
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) && path == DefaultAccountSetsPath {
+			logger.Debug("Using built-in account sets", "path", path)
+			data, err = []byte(defaultAccountSets), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var file setsFile
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &file)
+		case ".json":
+			err = json.Unmarshal(data, &file)
+		case ".toml":
+			err = toml.Unmarshal(data, &file)
+		default:
+			return nil, fmt.Errorf("unknown account sets file type %s, expected .yaml, .json or .toml", path)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid account sets file %s", path)
+		}
+
+		for network, kinds := range file.Networks {
+			for kind, accounts := range kinds {
+				sets = append(sets, namedSet{
+					name:     kind + "-" + network,
+					kind:     kind,
+					network:  network,
+					accounts: accounts,
+				})
+			}
+		}
+	}
+
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].name < sets[j].name
+	})
+
+	return sets, nil
+}
+
+// selectAccountSets returns the sets matched by any of include, or all when include is empty, minus those matched by exclude.
+// Selectors matching no set are an error, as they are most likely typos.
+func selectAccountSets(sets []namedSet, include, exclude []string) ([]namedSet, error) {
+	for _, selector := range append(append([]string{}, include...), exclude...) {
+		found := false
+		for _, s := range sets {
+			found = found || s.matches(selector)
+		}
+		if !found {
+			return nil, fmt.Errorf("no account set matches %s", selector)
+		}
+	}
+
+	var selected []namedSet
+	for _, s := range sets {
+		included := len(include) == 0
+		for _, selector := range include {
+			included = included || s.matches(selector)
+		}
+		for _, selector := range exclude {
+			included = included && !s.matches(selector)
+		}
+		if included {
+			selected = append(selected, s)
+		}
+	}
+
+	return selected, nil
+}
+
+// addAccountSets adds the accounts of the sets, recording the kind of the set as source
+func addAccountSets(accountSet AccountSet, sets []namedSet) error {
+	for _, s := range sets {
+		logger.Info("Adding account set", "set", s.name, "accounts", len(s.accounts))
+		for _, elem := range s.accounts {
+			acc, err := ParseAccount(elem)
+			if err != nil {
+				return errors.Wrapf(err, "set %s", s.name)
+			}
+			accountSet.add(acc, Provenance{Source: s.kind, Set: s.name})
+		}
+	}
+
+	return nil
+}
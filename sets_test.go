@@ -0,0 +1,94 @@
+package account_scraper
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDefaultAccountSetsGenerated(t *testing.T) {
+	data, err := ioutil.ReadFile(DefaultAccountSetsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != defaultAccountSets {
+		t.Errorf("built-in account sets differ from %s, run go generate", DefaultAccountSetsPath)
+	}
+}
+
+func TestLoadBuiltInAccountSets(t *testing.T) {
+	fromFile, err := loadAccountSets([]string{DefaultAccountSetsPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "sets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	builtIn, err := loadAccountSets([]string{DefaultAccountSetsPath})
+	if err != nil {
+		t.Fatalf("loading the default sets without the file: %v", err)
+	}
+	if len(builtIn) != len(fromFile) || len(builtIn) == 0 {
+		t.Errorf("loaded %d built-in sets, want the %d of the file", len(builtIn), len(fromFile))
+	}
+
+	if _, err := loadAccountSets([]string{"other/accounts.yaml"}); err == nil {
+		t.Error("loading a missing file other than the default succeeded")
+	}
+}
+
+func TestSelectAccountSets(t *testing.T) {
+	sets := []namedSet{
+		{name: "genesis-main", kind: "genesis", network: "main"},
+		{name: "genesis-amber", kind: "genesis", network: "amber"},
+		{name: "test-amber", kind: "test", network: "amber"},
+	}
+
+	tests := []struct {
+		include, exclude []string
+		want             []string
+		err              bool
+	}{
+		{nil, nil, []string{"genesis-main", "genesis-amber", "test-amber"}, false},
+		{[]string{"amber"}, nil, []string{"genesis-amber", "test-amber"}, false},
+		{[]string{"amber"}, []string{"test"}, []string{"genesis-amber"}, false},
+		{[]string{"genesis-main"}, nil, []string{"genesis-main"}, false},
+		{nil, []string{"genesis"}, []string{"test-amber"}, false},
+		{[]string{"mian"}, nil, nil, true},
+	}
+
+	for _, test := range tests {
+		selected, err := selectAccountSets(sets, test.include, test.exclude)
+		if (err != nil) != test.err {
+			t.Fatalf("include %v exclude %v: got error %v, want error %t", test.include, test.exclude, err, test.err)
+		}
+
+		var names []string
+		for _, s := range selected {
+			names = append(names, s.name)
+		}
+		if len(names) != len(test.want) {
+			t.Errorf("include %v exclude %v selected %v, want %v", test.include, test.exclude, names, test.want)
+			continue
+		}
+		for i := range names {
+			if names[i] != test.want[i] {
+				t.Errorf("include %v exclude %v selected %v, want %v", test.include, test.exclude, names, test.want)
+				break
+			}
+		}
+	}
+}
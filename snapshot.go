@@ -0,0 +1,112 @@
+package account_scraper
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/pkg/errors"
+)
+
+// DefaultSnapshotPath is where the snapshot is written unless configured otherwise
+const DefaultSnapshotPath = "build/snapshot.json"
+
+// SnapshotOptions configures a Snapshot run
+type SnapshotOptions struct {
+	URL string
+	// At is the block to take the snapshot at: a block number, a block hash or one of BlockLatest and BlockFinalized.
+	// A hash is used as given, also for blocks which are not canonical. Defaults to BlockFinalized.
+	At string
+	// Output is the file the balances are written to, defaults to DefaultSnapshotPath
+	Output string
+	// Format is the encoding of Output: scale, json or csv. Derived from its extension when empty.
+	Format Format
+	// Accounts optionally writes the plain account list to this file too, in the format matching its extension,
+	// so it can be diffed against the output of Process
+	Accounts   string
+	SS58Prefix *uint16
+	// MaxAttempts and Backoff configure the retries of RPC calls, see Options
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// Snapshot writes every account in System.Account storage at a block along with its balances
+func Snapshot(ctx context.Context, opts SnapshotOptions) error {
+	conn, err := newConnection(ctx, opts.URL, opts.MaxAttempts, opts.Backoff)
+	if err != nil {
+		return err
+	}
+
+	prefix := CentrifugePrefix
+	if opts.SS58Prefix != nil {
+		prefix = *opts.SS58Prefix
+	}
+	// checked up front, the addresses are only encoded once the work is done
+	err = CheckSS58Prefix(uint(prefix))
+	if err != nil {
+		return err
+	}
+
+	output := opts.Output
+	if output == "" {
+		output = DefaultSnapshotPath
+	}
+	format, err := balancesFormatFor(output, opts.Format)
+	if err != nil {
+		return err
+	}
+
+	at := opts.At
+	if at == "" {
+		at = BlockFinalized
+	}
+
+	var number uint64
+	var hash types.Hash
+	err = conn.call(ctx, func(api *gsrpc.SubstrateAPI) (err error) {
+		number, hash, err = resolveBlockHash(api, at)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "Error Resolving Block")
+	}
+
+	logger.Info("Taking snapshot of System.Account", "block", number, "hash", hash.Hex())
+	balances, err := stateBalances(ctx, conn, hash)
+	if err != nil {
+		return errors.Wrap(err, "Error Reading State")
+	}
+
+	data, err := encodeBalances(balances, format, prefix)
+	if err != nil {
+		return err
+	}
+
+	err = saveFile(output, data)
+	if err != nil {
+		return errors.Wrap(err, "Error Saving Snapshot")
+	}
+
+	total := new(big.Int)
+	accountSet := make(AccountSet)
+	for _, b := range balances {
+		total.Add(total, b.Total())
+		accountSet.add(b.Account, Provenance{Source: SourceState, BlockNumber: number, BlockHash: hash.Hex()})
+	}
+
+	if opts.Accounts != "" {
+		accountsFormat, err := formatFor(opts.Accounts, "")
+		if err != nil {
+			return err
+		}
+		_, err = encodeAndSave(accountSet, opts.Accounts, accountsFormat, prefix)
+		if err != nil {
+			return errors.Wrap(err, "Error Saving Accounts")
+		}
+	}
+
+	logger.Info("Saved snapshot", "path", output, "accounts", len(balances), "total", total.String(), "sha256", checksum(data))
+	return nil
+}
@@ -0,0 +1,159 @@
+package account_scraper
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"golang.org/x/crypto/blake2b"
+)
+
+// CentrifugePrefix is the SS58 network prefix of the Centrifuge Chain
+const CentrifugePrefix uint16 = 36
+
+// MaxSS58Prefix is the highest network prefix SS58 can encode. Prefixes up to 63 take one byte, higher ones two.
+const MaxSS58Prefix uint16 = 16383
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var ss58Context = []byte("SS58PRE")
+
+func ss58Checksum(data []byte) []byte {
+	h := blake2b.Sum512(append(append([]byte{}, ss58Context...), data...))
+	return h[:2]
+}
+
+// CheckSS58Prefix returns an error for network prefixes SS58 cannot encode.
+// It takes a uint so wider values, such as flags, are checked before being narrowed to a prefix.
+func CheckSS58Prefix(prefix uint) error {
+	if prefix > uint(MaxSS58Prefix) {
+		return fmt.Errorf("SS58 prefix %d is not supported, the maximum is %d", prefix, MaxSS58Prefix)
+	}
+
+	return nil
+}
+
+// encodeSS58Prefix returns the bytes of a network prefix. Prefixes from 64 take two bytes whose first has
+// its upper bits set to 01, so it does not overlap the single byte prefixes.
+func encodeSS58Prefix(prefix uint16) []byte {
+	if prefix < 64 {
+		return []byte{byte(prefix)}
+	}
+
+	return []byte{
+		byte((prefix&0x00fc)>>2) | 0x40,
+		byte(prefix>>8) | byte((prefix&0x0003)<<6),
+	}
+}
+
+// SS58Encode returns the SS58 address of the account for the given network prefix, up to MaxSS58Prefix
+func SS58Encode(acc types.AccountID, prefix uint16) (string, error) {
+	err := CheckSS58Prefix(uint(prefix))
+	if err != nil {
+		return "", err
+	}
+
+	data := append(encodeSS58Prefix(prefix), acc[:]...)
+	return base58Encode(append(data, ss58Checksum(data)...)), nil
+}
+
+// SS58Decode parses an SS58 address with a one or two byte network prefix and returns the account and the prefix
+func SS58Decode(address string) (types.AccountID, uint16, error) {
+	data, err := base58Decode(address)
+	if err != nil {
+		return types.AccountID{}, 0, err
+	}
+
+	if len(data) == 0 || data[0] > 127 {
+		return types.AccountID{}, 0, fmt.Errorf("invalid SS58 address %s: unsupported prefix", address)
+	}
+
+	prefixLen := 1
+	if data[0] >= 64 {
+		prefixLen = 2
+	}
+
+	// prefix, 32 byte account, two byte checksum
+	if len(data) != prefixLen+32+2 {
+		return types.AccountID{}, 0, fmt.Errorf("invalid SS58 address %s: unexpected length %d", address, len(data))
+	}
+
+	body := data[:prefixLen+32]
+	if !bytes.Equal(ss58Checksum(body), data[prefixLen+32:]) {
+		return types.AccountID{}, 0, fmt.Errorf("invalid SS58 address %s: checksum mismatch", address)
+	}
+
+	prefix := uint16(data[0])
+	if prefixLen == 2 {
+		prefix = uint16(data[0]&0x3f)<<2 | uint16(data[1]>>6) | uint16(data[1]&0x3f)<<8
+	}
+
+	return types.NewAccountID(body[prefixLen:]), prefix, nil
+}
+
+// ParseAccount parses an account given either as hex encoded public key or as SS58 address of any network
+func ParseAccount(s string) (types.AccountID, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") {
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return types.AccountID{}, fmt.Errorf("invalid account %s: %s", s, err.Error())
+		}
+		if len(b) != 32 {
+			return types.AccountID{}, fmt.Errorf("invalid account %s: expected 32 bytes, got %d", s, len(b))
+		}
+		return types.NewAccountID(b), nil
+	}
+
+	acc, _, err := SS58Decode(s)
+	return acc, err
+}
+
+func base58Encode(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+
+	// leading zero bytes are encoded as leading ones
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range s {
+		i := bytes.IndexRune([]byte(base58Alphabet), c)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(i)))
+	}
+
+	var zeros int
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
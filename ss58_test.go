@@ -0,0 +1,123 @@
+package account_scraper
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// alice is the public key of the //Alice development account
+var alice = types.NewAccountID(hexutil.MustDecode("0xd43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d"))
+
+func TestSS58(t *testing.T) {
+	tests := []struct {
+		prefix  uint16
+		address string
+	}{
+		{0, "15oF4uVJwmo4TdGW7VfQxNLavjCXviqxT9S1MgbjMNHr6Sp5"},
+		{2, "HNZata7iMYWmk5RvZRTiAsSDhV8366zq2YGb3tLH5Upf74F"},
+		{CentrifugePrefix, "4g8zNcypnFHE5jqCifLGYoutCCM7uKWhF1NjWHka29hQE2rx"},
+		{42, "5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY"},
+		// two byte prefixes
+		{64, "cEaNSpz4PxFcZ7nT1VEKrKewH67rfx6MfcM6yKojyyPz7qaqp"},
+		{255, "yGHXkYLYqxijLKKfd9Q2CB9shRVu8rPNBS53wvwGTutYg4zTg"},
+		{1284, "VdvKmYJfD4VXA9fzz1SbmCo2eYHSzUFbaDCZSuaNKJAe8YNg6"},
+		{MaxSS58Prefix, "yNa8JpqfFB3q8A29rCwSgxvdU94ufJw2yKKxDgznS5m1PoFvn"},
+	}
+
+	for _, test := range tests {
+		address, err := SS58Encode(alice, test.prefix)
+		if err != nil {
+			t.Fatalf("SS58Encode with prefix %d: %v", test.prefix, err)
+		}
+		if address != test.address {
+			t.Errorf("SS58Encode with prefix %d = %s, want %s", test.prefix, address, test.address)
+		}
+
+		acc, prefix, err := SS58Decode(test.address)
+		if err != nil {
+			t.Fatalf("SS58Decode(%s): %v", test.address, err)
+		}
+		if acc != alice || prefix != test.prefix {
+			t.Errorf("SS58Decode(%s) = %x with prefix %d, want %x with prefix %d", test.address, acc, prefix, alice, test.prefix)
+		}
+	}
+}
+
+func TestSS58Invalid(t *testing.T) {
+	_, err := SS58Encode(alice, MaxSS58Prefix+1)
+	if err == nil {
+		t.Errorf("SS58Encode with prefix %d succeeded", MaxSS58Prefix+1)
+	}
+	for _, prefix := range []uint{uint(MaxSS58Prefix) + 1, 70000} {
+		if err := CheckSS58Prefix(prefix); err == nil {
+			t.Errorf("CheckSS58Prefix(%d) succeeded", prefix)
+		}
+	}
+	if err := CheckSS58Prefix(uint(MaxSS58Prefix)); err != nil {
+		t.Errorf("CheckSS58Prefix(%d) failed: %v", MaxSS58Prefix, err)
+	}
+
+	for _, address := range []string{
+		"",
+		// checksum altered
+		"5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQZ",
+		// truncated
+		"5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKut",
+		// not base58
+		"0GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY",
+	} {
+		if _, _, err := SS58Decode(address); err == nil {
+			t.Errorf("SS58Decode(%q) succeeded", address)
+		}
+	}
+}
+
+func TestBase58(t *testing.T) {
+	tests := []struct {
+		data    []byte
+		encoded string
+	}{
+		{[]byte{}, ""},
+		{[]byte{0}, "1"},
+		{[]byte{0, 0, 1}, "112"},
+		{[]byte("hello world"), "StV1DL6CwTryKyV"},
+		{[]byte{0xff, 0xff}, "LUv"},
+	}
+
+	for _, test := range tests {
+		if got := base58Encode(test.data); got != test.encoded {
+			t.Errorf("base58Encode(%x) = %s, want %s", test.data, got, test.encoded)
+		}
+
+		data, err := base58Decode(test.encoded)
+		if err != nil {
+			t.Fatalf("base58Decode(%s): %v", test.encoded, err)
+		}
+		if !bytes.Equal(data, test.data) {
+			t.Errorf("base58Decode(%s) = %x, want %x", test.encoded, data, test.data)
+		}
+	}
+}
+
+func TestParseAccount(t *testing.T) {
+	for _, s := range []string{
+		"0xd43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d",
+		" 5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY\n",
+		"yGHXkYLYqxijLKKfd9Q2CB9shRVu8rPNBS53wvwGTutYg4zTg",
+	} {
+		acc, err := ParseAccount(s)
+		if err != nil {
+			t.Fatalf("ParseAccount(%q): %v", s, err)
+		}
+		if acc != alice {
+			t.Errorf("ParseAccount(%q) = %x, want %x", s, acc, alice)
+		}
+	}
+
+	if _, err := ParseAccount("0xd43593c7"); err == nil {
+		t.Error("ParseAccount of a short key succeeded")
+	}
+}
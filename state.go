@@ -0,0 +1,101 @@
+package account_scraper
+
+import (
+	"context"
+	"fmt"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/centrifuge/go-substrate-rpc-client/xxhash"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// keysPageSize is the number of keys requested per state_getKeysPaged call
+	keysPageSize = 1000
+	// SourceState is the source of accounts read from System.Account storage rather than events
+	SourceState = "System.Account"
+)
+
+// storagePrefix returns the key prefix shared by all entries of a storage map
+func storagePrefix(module, storage string) types.StorageKey {
+	return append(xxhash.New128([]byte(module)).Sum(nil), xxhash.New128([]byte(storage)).Sum(nil)...)
+}
+
+// forEachKeysPage pages through the storage keys starting with prefix at the given block, calling fn with every page
+func forEachKeysPage(ctx context.Context, conn *connection, prefix types.StorageKey, hash types.Hash, fn func(keys []types.StorageKey) error) error {
+	startKey := prefix
+	for {
+		var page []string
+		err := conn.call(ctx, func(api *gsrpc.SubstrateAPI) error {
+			return api.Client.Call(&page, "state_getKeysPaged", prefix.Hex(), keysPageSize, startKey.Hex(), hash.Hex())
+		})
+		if err != nil {
+			return err
+		}
+
+		keys := make([]types.StorageKey, 0, len(page))
+		for _, elem := range page {
+			b, err := hexutil.Decode(elem)
+			if err != nil {
+				return fmt.Errorf("invalid storage key %s: %s", elem, err.Error())
+			}
+			keys = append(keys, types.NewStorageKey(b))
+		}
+
+		if len(keys) > 0 {
+			err = fn(keys)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(keys) < keysPageSize {
+			return nil
+		}
+		startKey = keys[len(keys)-1]
+	}
+}
+
+// accountFromKey returns the account of a System.Account storage key.
+// This only works with hashers appending the account to its hash, Blake2_128Concat and Twox64Concat.
+func accountFromKey(prefix, key types.StorageKey) (types.AccountID, error) {
+	hashed := len(key) - len(prefix) - len(types.AccountID{})
+	if hashed != 16 && hashed != 8 {
+		return types.AccountID{}, fmt.Errorf("cannot recover account from storage key %s, System.Account must use a concat hasher", key.Hex())
+	}
+
+	return types.NewAccountID(key[len(key)-len(types.AccountID{}):]), nil
+}
+
+// addStateAccounts adds every account in System.Account storage at the given block
+func addStateAccounts(ctx context.Context, conn *connection, accountSet AccountSet, number uint64) error {
+	var hash types.Hash
+	err := conn.call(ctx, func(api *gsrpc.SubstrateAPI) (err error) {
+		hash, err = api.RPC.Chain.GetBlockHash(number)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	prefix := storagePrefix("System", "Account")
+	count := 0
+	err = forEachKeysPage(ctx, conn, prefix, hash, func(keys []types.StorageKey) error {
+		for _, key := range keys {
+			acc, err := accountFromKey(prefix, key)
+			if err != nil {
+				return err
+			}
+			accountSet.add(acc, Provenance{Source: SourceState, BlockNumber: number, BlockHash: hash.Hex()})
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Found accounts in state", "accounts", count, "block", number)
+	return nil
+}
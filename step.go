@@ -0,0 +1,133 @@
+package account_scraper
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	gsrpc "github.com/centrifuge/go-substrate-rpc-client"
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+const (
+	// DefaultStep is the number of blocks queried at once when starting a run
+	DefaultStep = uint64(5000)
+	maxStep     = uint64(200000)
+	// growBelowBytes is the size of event data under which a range is considered small enough to grow the step.
+	// The websocket client refuses responses over 5MB, hex encoding doubles the size on the wire.
+	growBelowBytes = 512 * 1024
+)
+
+// tooLargeMessages are fragments of errors returned when a state_queryStorage response exceeds the size limit
+// of the node or the client. Timeouts are not among them, they are transient and retried as they are.
+var tooLargeMessages = []string{
+	"read limit exceeded",
+	"too big",
+	"too large",
+}
+
+func isTooLarge(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, m := range tooLargeMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stepSizer tracks the number of blocks to query at once, shrinking it when ranges fail for being too large
+// and growing it while responses stay small
+type stepSizer struct {
+	mu   sync.Mutex
+	step uint64
+}
+
+func newStepSizer(step uint64) *stepSizer {
+	if step == 0 {
+		step = DefaultStep
+	}
+
+	return &stepSizer{step: step}
+}
+
+func (s *stepSizer) next() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.step
+}
+
+// shrink lowers the step below the size of a range that failed for being too large
+func (s *stepSizer) shrink(failed uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	half := failed / 2
+	if half < 1 {
+		half = 1
+	}
+	if half < s.step {
+		logger.Debug("Shrinking step", "blocks", half)
+		s.step = half
+	}
+}
+
+// grow doubles the step when a range of at least the current step returned less than growBelowBytes
+func (s *stepSizer) grow(blocks uint64, size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if size >= growBelowBytes || blocks < s.step || s.step >= maxStep {
+		return
+	}
+
+	s.step *= 2
+	if s.step > maxStep {
+		s.step = maxStep
+	}
+	logger.Debug("Growing step", "blocks", s.step)
+}
+
+// processAdaptive processes the blocks lower to upper, splitting the range in half whenever the node fails
+// to answer for it being too large. It returns the hash of the upper block.
+func (s *scan) processAdaptive(ctx context.Context, lower, upper uint64, found *findings) (types.Hash, error) {
+	var hash types.Hash
+	var size int
+	rangeFound := newFindings()
+	attempt := func() error {
+		return s.conn.call(ctx, func(api *gsrpc.SubstrateAPI) (err error) {
+			// start over on every attempt so a partially processed range is not merged
+			rangeFound = newFindings()
+			hash, size, err = s.processRange(api, lower, upper, rangeFound)
+			return err
+		})
+	}
+
+	var err error
+	if lower == upper {
+		// a single block cannot be split, so it is retried with backoff in case the node manages it later
+		err = retryOn(ctx, errTooLarge, s.conn.maxAttempts, s.conn.backoff, attempt)
+	} else {
+		err = attempt()
+	}
+
+	if err != nil {
+		if classify(err) != errTooLarge || lower == upper {
+			return types.Hash{}, err
+		}
+
+		s.sizer.shrink(upper - lower + 1)
+		middle := lower + (upper-lower)/2
+		logger.Debug("Range too large, splitting", "lower", lower, "upper", upper, "middle", middle)
+		_, err = s.processAdaptive(ctx, lower, middle, found)
+		if err != nil {
+			return types.Hash{}, err
+		}
+
+		return s.processAdaptive(ctx, middle+1, upper, found)
+	}
+
+	s.sizer.grow(upper-lower+1, size)
+	found.merge(rangeFound)
+
+	return hash, nil
+}
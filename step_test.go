@@ -0,0 +1,80 @@
+package account_scraper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestIsTooLarge(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{"websocket: read limit exceeded", true},
+		{"Response is too big", true},
+		{"message too large", true},
+		{"read tcp 127.0.0.1:9944: i/o timeout", false},
+		{"request timed out", false},
+		{"value exceeds maximum", false},
+		{"connection reset by peer", false},
+	}
+
+	for _, test := range tests {
+		if got := isTooLarge(errors.New(test.msg)); got != test.want {
+			t.Errorf("isTooLarge(%q) = %t, want %t", test.msg, got, test.want)
+		}
+	}
+}
+
+func TestRetryOnTooLarge(t *testing.T) {
+	attempts := 0
+	err := retryOn(context.Background(), errTooLarge, 3, 0, func() error {
+		attempts++
+		return errors.New("websocket: read limit exceeded")
+	})
+	if err == nil || attempts != 3 {
+		t.Errorf("got %d attempts and error %v, want 3 and an error", attempts, err)
+	}
+
+	attempts = 0
+	err = retryOn(context.Background(), errTooLarge, 3, 0, func() error {
+		attempts++
+		return errors.New("connection reset by peer")
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("got %d attempts and error %v, want 1 and an error", attempts, err)
+	}
+}
+
+func TestStepSizer(t *testing.T) {
+	s := newStepSizer(0)
+	if s.next() != DefaultStep {
+		t.Fatalf("got step %d, want %d", s.next(), DefaultStep)
+	}
+
+	s.shrink(1000)
+	if s.next() != 500 {
+		t.Errorf("got step %d after shrinking, want 500", s.next())
+	}
+	s.shrink(1)
+	if s.next() != 1 {
+		t.Errorf("got step %d after shrinking a single block, want 1", s.next())
+	}
+
+	s.grow(1, growBelowBytes)
+	if s.next() != 1 {
+		t.Errorf("got step %d after a large response, want 1", s.next())
+	}
+	s.grow(1, 0)
+	if s.next() != 2 {
+		t.Errorf("got step %d after growing, want 2", s.next())
+	}
+
+	s = newStepSizer(maxStep)
+	s.grow(maxStep, 0)
+	if s.next() != maxStep {
+		t.Errorf("got step %d, want it capped at %d", s.next(), maxStep)
+	}
+}
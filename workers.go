@@ -0,0 +1,133 @@
+package account_scraper
+
+import (
+	"context"
+	"sync"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+	"github.com/pkg/errors"
+)
+
+// blockRange is a window of blocks processed by a single processRange call
+type blockRange struct {
+	index        int
+	lower, upper uint64
+}
+
+// rangeResult holds the findings of a worker in a blockRange
+type rangeResult struct {
+	blockRange
+	hash  types.Hash
+	found *findings
+	err   error
+}
+
+// processRanges fetches and decodes the blocks from to to (both inclusive) with the given number of workers,
+// in ranges sized by the sizer of the scan.
+// Results are merged into found strictly in range order, calling done with the new accounts after each merge,
+// so found and anything recorded in done never depend on the order the workers finish in.
+// Only the calling goroutine touches found.
+// Once the scan is stopped no further ranges are started and processRanges returns after merging the ranges in flight.
+// When ctx is done the ranges in flight fail and it returns with the error.
+func (s *scan) processRanges(ctx context.Context, from, to uint64, workers int, found *findings, done func(r blockRange, hash types.Hash, added []types.AccountID) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	process := s.process
+	if process == nil {
+		process = s.processAdaptive
+	}
+
+	jobs := make(chan blockRange)
+	results := make(chan rangeResult)
+	quit := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				rangeFound := newFindings()
+				hash, err := process(ctx, r.lower, r.upper, rangeFound)
+				select {
+				case results <- rangeResult{blockRange: r, hash: hash, found: rangeFound, err: err}:
+				case <-quit:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		// ranges are cut as they are handed out, so they follow the step as the sizer adapts it
+		index := 0
+		for lower := from; lower <= to; {
+			// after a stop the ranges handed out already are finished, but no further ones
+			if s.stopped() {
+				return
+			}
+
+			upper := lower + s.sizer.next() - 1
+			if upper > to || upper < lower {
+				upper = to
+			}
+
+			select {
+			case jobs <- blockRange{index: index, lower: lower, upper: upper}:
+			case <-quit:
+				return
+			case <-s.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+
+			index++
+			if upper == to {
+				return
+			}
+			lower = upper + 1
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// stop the dispatcher and workers on return, then wait for them to finish
+	defer func() {
+		close(quit)
+		for range results {
+		}
+	}()
+
+	pending := make(map[int]rangeResult)
+	next := 0
+	for res := range results {
+		if res.err != nil {
+			return errors.Wrapf(res.err, "range %d - %d", res.lower, res.upper)
+		}
+
+		pending[res.index] = res
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			added := found.merge(r.found)
+
+			err := done(r.blockRange, r.hash, added)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
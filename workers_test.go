@@ -0,0 +1,139 @@
+package account_scraper
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/types"
+)
+
+// rangeAccount is the account found in the range starting at lower
+func rangeAccount(lower uint64) types.AccountID {
+	var acc types.AccountID
+	acc[0] = byte(lower/10) + 1
+	return acc
+}
+
+func TestProcessRangesMergeInOrder(t *testing.T) {
+	var reaped types.AccountID
+	reaped[0] = 0xff
+
+	// every range waits for the one after it, so the workers finish last range first
+	finished := make(map[uint64]chan struct{})
+	for lower := uint64(0); lower < 40; lower += 10 {
+		finished[lower] = make(chan struct{})
+	}
+
+	var mu sync.Mutex
+	var finishOrder []uint64
+	s := &scan{
+		sizer: newStepSizer(10),
+		process: func(ctx context.Context, lower, upper uint64, found *findings) (types.Hash, error) {
+			if next, ok := finished[upper+1]; ok {
+				<-next
+			}
+
+			found.accounts.add(rangeAccount(lower), Provenance{Source: "Balances.Endowed", BlockNumber: lower})
+			switch lower {
+			case 0:
+				found.kill(reaped, 5)
+			case 20:
+				found.revive(reaped, 25)
+			}
+
+			mu.Lock()
+			finishOrder = append(finishOrder, lower)
+			mu.Unlock()
+			close(finished[lower])
+			return types.NewHash([]byte{byte(upper)}), nil
+		},
+	}
+
+	found := newFindings()
+	var merged []uint64
+	err := s.processRanges(context.Background(), 0, 39, 4, found, func(r blockRange, hash types.Hash, added []types.AccountID) error {
+		if r.index != len(merged) {
+			t.Errorf("range %d merged as number %d", r.index, len(merged))
+		}
+		if len(added) != 1 || added[0] != rangeAccount(r.lower) {
+			t.Errorf("range %d - %d added %v", r.lower, r.upper, added)
+		}
+		if hash != types.NewHash([]byte{byte(r.upper)}) {
+			t.Errorf("range %d - %d got hash %s", r.lower, r.upper, hash.Hex())
+		}
+		merged = append(merged, r.lower)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFinished := []uint64{30, 20, 10, 0}
+	for i := range wantFinished {
+		if i >= len(finishOrder) || finishOrder[i] != wantFinished[i] {
+			t.Fatalf("workers finished %v, want %v", finishOrder, wantFinished)
+		}
+	}
+	wantMerged := []uint64{0, 10, 20, 30}
+	for i := range wantMerged {
+		if i >= len(merged) || merged[i] != wantMerged[i] {
+			t.Fatalf("merged %v, want %v", merged, wantMerged)
+		}
+	}
+	if len(found.accounts) != 4 {
+		t.Errorf("found %d accounts, want 4", len(found.accounts))
+	}
+	if _, ok := found.killed[reaped]; ok {
+		t.Error("account revived after its kill is still killed")
+	}
+}
+
+func TestProcessRangesStop(t *testing.T) {
+	stop := make(chan struct{})
+
+	var mu sync.Mutex
+	started := make(map[uint64]bool)
+	s := &scan{
+		sizer: newStepSizer(10),
+		stop:  stop,
+		process: func(ctx context.Context, lower, upper uint64, found *findings) (types.Hash, error) {
+			mu.Lock()
+			started[lower] = true
+			mu.Unlock()
+			if lower == 0 {
+				close(stop)
+			}
+
+			found.accounts.add(rangeAccount(lower), Provenance{Source: "Balances.Endowed", BlockNumber: lower})
+			return types.Hash{}, nil
+		},
+	}
+
+	found := newFindings()
+	var merged []uint64
+	err := s.processRanges(context.Background(), 0, 99, 2, found, func(r blockRange, hash types.Hash, added []types.AccountID) error {
+		merged = append(merged, r.lower)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the ranges in flight when stopping are merged, in order, without starting the rest
+	if len(merged) == 0 || len(merged) >= 10 {
+		t.Fatalf("merged %d ranges, want some but not all", len(merged))
+	}
+	if len(merged) != len(started) {
+		t.Errorf("merged %d of the %d ranges started", len(merged), len(started))
+	}
+	for i, lower := range merged {
+		if lower != uint64(i)*10 {
+			t.Errorf("merged %v, want consecutive ranges from 0", merged)
+			break
+		}
+	}
+	if len(found.accounts) != len(merged) {
+		t.Errorf("found %d accounts in %d ranges", len(found.accounts), len(merged))
+	}
+}